@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RunCommand(args []string, config *config.Config, db *gorm.DB, redis *redis.Client) error {
+	switch args[0] {
+	case "keys":
+		return KeysCommand(args[1:], config, db, redis)
+	case "leaderboard":
+		return LeaderboardCommand(args[1:], config, db, redis)
+	case "reconcile":
+		return ReconcileCommand(args[1:], config, db, redis)
+	}
+
+	return errors.New("unknown command: " + args[0])
+}
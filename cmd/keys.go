@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// galax keys <create|list|revoke> [flags]
+func KeysCommand(args []string, config *config.Config, db *gorm.DB, redis *redis.Client) error {
+	if len(args) == 0 {
+		return errors.New("usage: keys <create|list|revoke>")
+	}
+
+	keys := repository.CreateAPIKeyRepository(db, redis, config)
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+
+		name := flags.String("name", "", "unique name of the key")
+		scopes := flags.String("scopes", "", "comma separated scopes, such as account:read,cash:write")
+		addresses := flags.String("addresses", "", "comma separated addresses or CIDR ranges allowed to use the key")
+		expires := flags.Duration("expires", 0, "lifetime of the key, such as 720h, never expires when zero")
+		signing := flags.Bool("signing", false, "require HMAC signed requests, printing the signing secret")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if len(*name) == 0 || len(*scopes) == 0 {
+			return errors.New("name and scopes are required")
+		}
+
+		targetScopes := []data.Scope{}
+
+		for _, scope := range strings.Split(*scopes, ",") {
+			if scope = strings.TrimSpace(scope); len(scope) == 0 {
+				continue
+			}
+
+			// A mistyped scope would only fail every request later on.
+			if !data.Scope(scope).IsKnown() {
+				known := []string{}
+
+				for _, target := range data.Scopes {
+					known = append(known, string(target))
+				}
+
+				return fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(known, ","))
+			}
+
+			targetScopes = append(targetScopes, data.Scope(scope))
+		}
+
+		targetAddresses := []string{}
+
+		for _, address := range strings.Split(*addresses, ",") {
+			if address = strings.TrimSpace(address); len(address) > 0 {
+				targetAddresses = append(targetAddresses, address)
+			}
+		}
+
+		var expireAt *time.Time
+
+		if *expires > 0 {
+			target := time.Now().Add(*expires)
+			expireAt = &target
+		}
+
+		plain, secret, key, err := keys.CreateKey(*name, targetScopes, targetAddresses, expireAt, *signing)
+
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msg("Created API key '" + key.GetName() + "', store it now as it cannot be shown again.")
+		fmt.Println(plain)
+
+		if key.RequiresSigning() {
+			log.Info().Msg("The key only accepts signed requests, sign them with the following secret.")
+			fmt.Println(secret)
+		}
+
+		return nil
+	case "list":
+		result, err := keys.ListKeys()
+
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "NAME\tSCOPES\tADDRESSES\tSIGNED\tEXPIRES\tSTATUS")
+
+		for _, key := range result {
+			scopes := []string{}
+
+			for _, scope := range key.GetScopes() {
+				scopes = append(scopes, string(scope))
+			}
+
+			expires := "never"
+
+			if key.GetExpireAt() != nil {
+				expires = key.GetExpireAt().Format(time.RFC3339)
+			}
+
+			status := "active"
+
+			if key.IsRevoked() {
+				status = "revoked"
+			} else if key.IsExpired() {
+				status = "expired"
+			}
+
+			fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%t\t%s\t%s\n",
+				key.GetName(),
+				strings.Join(scopes, ","),
+				strings.Join(key.GetAddresses(), ","),
+				key.RequiresSigning(),
+				expires,
+				status,
+			)
+		}
+
+		return writer.Flush()
+	case "revoke":
+		flags := flag.NewFlagSet("keys revoke", flag.ContinueOnError)
+
+		name := flags.String("name", "", "name of the key to revoke")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if err := keys.RevokeKey(*name); err != nil {
+			return err
+		}
+
+		log.Info().Msg("Revoked API key '" + *name + "'.")
+
+		return nil
+	}
+
+	return errors.New("unknown keys command: " + args[0])
+}
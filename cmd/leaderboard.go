@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// galax leaderboard rebuild [--currency name]
+func LeaderboardCommand(args []string, config *config.Config, db *gorm.DB, redis *redis.Client) error {
+	if len(args) == 0 || args[0] != "rebuild" {
+		return errors.New("usage: leaderboard rebuild [--currency name]")
+	}
+
+	flags := flag.NewFlagSet("leaderboard rebuild", flag.ContinueOnError)
+
+	currency := flags.String("currency", "", "currency to rebuild, every currency when empty")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	currencies := []string{*currency}
+
+	if len(*currency) == 0 {
+		result, err := repository.CreateCurrencyRepository(db, config).ListCurrencies()
+
+		if err != nil {
+			return err
+		}
+
+		currencies = []string{}
+
+		for _, target := range result {
+			currencies = append(currencies, target.GetName())
+		}
+	}
+
+	leaderboard := repository.CreateLeaderboardRepository(db, redis, config)
+
+	for _, target := range currencies {
+		total, err := leaderboard.Rebuild(target)
+
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msgf("Rebuilt the %s leaderboard with %d accounts.", target, total)
+	}
+
+	return nil
+}
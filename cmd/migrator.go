@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/luiz-otavio/galax/internal/impl"
 	"github.com/luiz-otavio/galax/pkg/data"
 	"github.com/rs/zerolog/log"
@@ -11,8 +15,31 @@ func Migrate(db *gorm.DB) error {
 	interfaces := []interface{}{
 		impl.AccountImpl{},
 		impl.AuthenticationImpl{},
+		impl.SessionImpl{},
+		impl.RecoveryCodeImpl{},
+		impl.APIKeyImpl{},
+		impl.TransactionImpl{},
+		impl.CurrencyImpl{},
+		impl.GroupImpl{},
+		impl.GroupPermissionImpl{},
+		impl.GroupParentImpl{},
+		impl.TrackImpl{},
+		impl.CouponImpl{},
+		impl.RedemptionImpl{},
+		impl.PurchaseImpl{},
 		data.GroupInfo{},
 		data.MetadataSet{},
+		data.Wallet{},
+	}
+
+	if err := renameUniqueIds(db, interfaces); err != nil {
+		log.Fatal().Err(err).Msg("Failed to rename unique id columns.")
+		return err
+	}
+
+	if err := linkAuthentications(db); err != nil {
+		log.Fatal().Err(err).Msg("Failed to link authentications to their accounts.")
+		return err
 	}
 
 	if err := db.AutoMigrate(interfaces...); err != nil {
@@ -23,3 +50,97 @@ func Migrate(db *gorm.DB) error {
 	log.Info().Msg("Database migrated successfully.")
 	return nil
 }
+
+// Older releases named the unique id column "uuid", rename it before
+// AutoMigrate adds an empty "unique_id" column next to it.
+func renameUniqueIds(db *gorm.DB, interfaces []interface{}) error {
+	migrator := db.Migrator()
+
+	for _, model := range interfaces {
+		if !migrator.HasColumn(model, "uuid") || migrator.HasColumn(model, "unique_id") {
+			continue
+		}
+
+		if err := migrator.RenameColumn(model, "uuid", "unique_id"); err != nil {
+			return err
+		}
+
+		log.Info().Msgf("Renamed uuid column to unique_id on %T.", model)
+	}
+
+	return nil
+}
+
+// Authentications registered before they were bound to accounts may use an
+// unrelated unique id. Re-key them to the account with the same name, so the
+// foreign key created by AutoMigrate holds. Authentications without account
+// are never removed here, the migration fails listing them instead.
+func linkAuthentications(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	if !migrator.HasTable(&impl.AuthenticationImpl{}) || !migrator.HasTable(&impl.AccountImpl{}) {
+		return nil
+	}
+
+	linked := map[string]string{}
+	unlinked := []string{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var orphans []impl.AuthenticationImpl
+
+		err := tx.Select("unique_id", "username").
+			Where("unique_id NOT IN (?)", tx.Model(&impl.AccountImpl{}).Select("unique_id")).
+			Find(&orphans).Error
+
+		if err != nil {
+			return err
+		}
+
+		for _, orphan := range orphans {
+			var account impl.AccountImpl
+
+			err := tx.Where("username = ?", orphan.Username).First(&account).Error
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err != nil || tx.Where("unique_id = ?", account.UUID).First(&impl.AuthenticationImpl{}).Error == nil {
+				unlinked = append(unlinked, orphan.Username)
+				continue
+			}
+
+			if err := tx.Model(&impl.AuthenticationImpl{}).Where("unique_id = ?", orphan.UUID).UpdateColumn("unique_id", account.UUID).Error; err != nil {
+				return err
+			}
+
+			if migrator.HasTable(&impl.RecoveryCodeImpl{}) {
+				if err := tx.Model(&impl.RecoveryCodeImpl{}).Where("user = ?", orphan.UUID).UpdateColumn("user", account.UUID).Error; err != nil {
+					return err
+				}
+			}
+
+			linked[orphan.Username] = account.UUID
+		}
+
+		// Rolled back, so nothing is half linked while the operator sorts them out.
+		if len(unlinked) > 0 {
+			return fmt.Errorf(
+				"authentications without an account to link to: %s, create their accounts or remove them before starting",
+				strings.Join(unlinked, ", "),
+			)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for username, uniqueId := range linked {
+		log.Info().Msgf("Linked authentication %s to account %s.", username, uniqueId)
+	}
+
+	return nil
+}
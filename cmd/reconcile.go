@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luiz-otavio/galax/internal/reconciler"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// galax reconcile [--authority none|db|cache] [--output file]
+func ReconcileCommand(args []string, config *config.Config, db *gorm.DB, redis *redis.Client) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+
+	authority := flags.String("authority", "none", "copy kept on drift, none only reports, db reloads the cache and cache writes it to database")
+	output := flags.String("output", "", "file to write the JSON report, printed when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	target, err := reconciler.ParseAuthority(*authority)
+
+	if err != nil {
+		return err
+	}
+
+	report, err := CreateReconciler(config, db, redis).Reconcile(target)
+
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.MarshalIndent(report, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Checked %d cached accounts, %d drifted and %d repaired.", report.Checked, report.Drifted, report.Repaired)
+
+	if len(*output) > 0 {
+		return os.WriteFile(*output, append(encoded, '\n'), 0644)
+	}
+
+	_, err = os.Stdout.Write(append(encoded, '\n'))
+
+	return err
+}
+
+func CreateReconciler(config *config.Config, db *gorm.DB, redis *redis.Client) reconciler.Reconciler {
+	return reconciler.CreateReconciler(
+		db,
+		repository.CreateRedisRepository(redis, config),
+		repository.CreateLedgerRepository(db, config),
+		config,
+	)
+}
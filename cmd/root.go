@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/luiz-otavio/galax/internal/hasher"
+	"github.com/luiz-otavio/galax/internal/policy"
 	"github.com/luiz-otavio/galax/pkg/config"
 	"github.com/rs/zerolog/log"
 )
@@ -20,6 +22,8 @@ func Execute() {
 
 	os.Setenv("GALAX_DEBUGGING", fmt.Sprint(config.GetDebug()))
 
+	hasher.SetDefault(CreateHasher(config))
+
 	log.Info().Msg("Loaded configuration successfully.")
 	log.Info().Msg("Starting connectors...")
 
@@ -38,6 +42,16 @@ func Execute() {
 	}
 
 	log.Info().Msg("Migrated sources to database successfully.")
+
+	// Administrative commands run against the same connectors and exit
+	if len(os.Args) > 1 {
+		if err := RunCommand(os.Args[1:], config, db, redis); err != nil {
+			log.Fatal().Err(err).Msg("Command failed.")
+		}
+
+		return
+	}
+
 	log.Info().Msg("Starting routers...")
 
 	fiberApp := Listen(config, db, redis)
@@ -51,3 +65,40 @@ func Execute() {
 
 	fiberApp.Listen(config.GetBinding())
 }
+
+func CreateHasher(config *config.Config) hasher.Hasher {
+	bcrypt := hasher.CreateBcryptHasher(config.GetBcryptCost())
+	argon2 := hasher.CreateArgon2Hasher(
+		config.GetArgon2Memory(),
+		config.GetArgon2Iterations(),
+		config.GetArgon2Parallelism(),
+	)
+
+	switch config.GetPasswordAlgorithm() {
+	case "bcrypt":
+		return hasher.CreateMultiHasher(bcrypt, argon2)
+	case "argon2id", "":
+		return hasher.CreateMultiHasher(argon2, bcrypt)
+	}
+
+	log.Warn().Msg("Unknown password algorithm '" + config.GetPasswordAlgorithm() + "', falling back to argon2id.")
+
+	return hasher.CreateMultiHasher(argon2, bcrypt)
+}
+
+func CreatePolicy(config *config.Config) (policy.Policy, error) {
+	return policy.CreatePolicy(policy.Rules{
+		UsernamePattern: config.GetUsernamePattern(),
+
+		MinPassword: config.GetMinPassword(),
+		MaxPassword: config.GetMaxPassword(),
+
+		RequireLower:  config.GetRequireLower(),
+		RequireUpper:  config.GetRequireUpper(),
+		RequireDigit:  config.GetRequireDigit(),
+		RequireSymbol: config.GetRequireSymbol(),
+
+		DenyCommon:   config.GetDenyCommon(),
+		DenyUsername: config.GetDenyUsername(),
+	})
+}
@@ -6,9 +6,14 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/keyauth/v2"
+	"github.com/luiz-otavio/galax/internal/mojang"
+	"github.com/luiz-otavio/galax/internal/notifier"
+	"github.com/luiz-otavio/galax/internal/reconciler"
 	"github.com/luiz-otavio/galax/internal/repository"
 	"github.com/luiz-otavio/galax/internal/router"
+	"github.com/luiz-otavio/galax/internal/sweeper"
+	"github.com/luiz-otavio/galax/internal/token"
+	"github.com/luiz-otavio/galax/internal/util"
 	"github.com/luiz-otavio/galax/internal/worker"
 	"github.com/luiz-otavio/galax/pkg/config"
 	"github.com/rs/zerolog/log"
@@ -21,19 +26,96 @@ func Listen(config *config.Config, db *gorm.DB, redis *redis.Client) *fiber.App
 		DisableKeepalive:      true,
 	})
 
-	v1 := app.Group("/v1")
+	if len(config.GetKey()) > 0 {
+		log.Warn().Msg("Legacy api.key is enabled and holds every scope, prefer named keys.")
+	}
+
+	// Tokens signed with an empty or short key can be forged by anyone.
+	if len(config.GetSessionSecret()) < 32 {
+		log.Error().Msg("Session secret must have at least 32 bytes, set session.secret on config file.")
+		return nil
+	}
+
+	policy, err := CreatePolicy(config)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot compile the registration policy.")
+		return nil
+	}
 
 	worker := worker.CreateWorker(db)
+	worker.Initialize()
+
+	cache := repository.CreateRedisRepository(
+		redis,
+		config,
+	)
+
+	currencies := repository.CreateCurrencyRepository(db, config)
+
+	if err := currencies.Seed(); err != nil {
+		log.Error().Err(err).Msg("Cannot seed currencies.")
+		return nil
+	}
+
+	groups := repository.CreateGroupRepository(db, redis, config)
+
+	if err := groups.Seed(); err != nil {
+		log.Error().Err(err).Msg("Cannot seed groups.")
+		return nil
+	}
+
+	// Group names are validated against the registry from now on
+	util.SetGroupRegistry(groups)
+
+	ledger := repository.CreateLedgerRepository(db, config)
+	coupons := repository.CreateCouponRepository(db, ledger)
+	permissions := repository.CreatePermissionRepository(db, redis, groups, config)
+	tracks := repository.CreateTrackRepository(db, groups)
 
 	accountRouter := router.CreateAccountRouter(
 		db,
-		repository.CreateRedisRepository(
+		cache,
+		ledger,
+		currencies,
+		coupons,
+		repository.CreateLeaderboardRepository(
+			db,
+			redis,
+			config,
+		),
+		permissions,
+		tracks,
+		repository.CreateIdempotencyRepository(
 			redis,
 			config,
 		),
 		worker,
+		mojang.CreateSessionServer(
+			config.GetSessionServer(),
+			config.GetSessionServerTimeout(),
+		),
 	)
 
+	authority, err := reconciler.ParseAuthority(config.GetReconcileAuthority())
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot parse the reconciliation authority.")
+		return nil
+	}
+
+	reconciler := reconciler.CreateReconciler(db, cache, ledger, config)
+
+	if config.GetReconcileInterval() > 0 {
+		reconciler.Initialize(config.GetReconcileInterval(), authority)
+	}
+
+	sweeper := sweeper.CreateSweeper(db, redis, cache, groups, config)
+
+	if config.GetSweepInterval() > 0 {
+		sweeper.Initialize(config.GetSweepInterval())
+	}
+
 	// Listen to Ctrl + C
 	ch := make(chan os.Signal, 1)
 
@@ -45,6 +127,8 @@ func Listen(config *config.Config, db *gorm.DB, redis *redis.Client) *fiber.App
 		log.Info().Msg("Shutting down server...")
 
 		worker.Shutdown()
+		reconciler.Shutdown()
+		sweeper.Shutdown()
 
 		database, err := db.DB()
 
@@ -62,21 +146,82 @@ func Listen(config *config.Config, db *gorm.DB, redis *redis.Client) *fiber.App
 		}
 	}()
 
-	authRouter := router.CreateAuthRouter(db)
+	issuer := token.CreateIssuer(
+		config.GetSessionSecret(),
+		config.GetAccessLifetime(),
+		config.GetRefreshLifetime(),
+		redis,
+		config.GetSessionKey(),
+	)
+
+	authRouter := router.CreateAuthRouter(
+		db,
+		cache,
+		config,
+		issuer,
+		repository.CreateSessionRepository(
+			db,
+			redis,
+			worker,
+			config,
+		),
+		repository.CreateLockoutRepository(
+			redis,
+			config,
+		),
+		repository.CreateResetRepository(
+			redis,
+			config,
+		),
+		CreateNotifier(config, redis),
+		policy,
+	)
+
+	currencyRouter := router.CreateCurrencyRouter(currencies)
+	couponRouter := router.CreateCouponRouter(config, coupons, currencies)
+	groupRouter := router.CreateGroupRouter(groups, permissions)
+	trackRouter := router.CreateTrackRouter(tracks)
+
+	storeRouter := router.CreateStoreRouter(
+		config,
+		cache,
+		repository.CreateStoreRepository(db, ledger, config),
+		currencies,
+	)
+
+	// The store signs its webhooks instead of holding an API key, so it must come before the key middleware
+	storeRouter.TakeEndpoints(app.Group("/v1/store"))
+
+	// Add middleware to check if the key is valid, it must come before every route
+	v1 := app.Group("/v1", router.RequireAPIKey(
+		repository.CreateAPIKeyRepository(
+			db,
+			redis,
+			config,
+		),
+		config.GetKey(),
+		config.GetSigningSkew(),
+	))
 
 	accountRouter.TakeEndpoints(v1.Group("/account"))
 	authRouter.TakeEndpoints(v1.Group("/auth"))
+	currencyRouter.TakeEndpoints(v1.Group("/currencies"))
+	couponRouter.TakeEndpoints(v1.Group("/coupons"))
+	groupRouter.TakeEndpoints(v1.Group("/groups"))
+	trackRouter.TakeEndpoints(v1.Group("/tracks"))
 
-	// Add middleware to check if the key is valid
-	app.Use(keyauth.New(keyauth.Config{
-		Validator: func(ctx *fiber.Ctx, key string) (bool, error) {
-			if config.GetDebug() {
-				log.Debug().Msg("Income request from " + ctx.IP())
-			}
+	return app
+}
 
-			return key == config.GetKey(), nil
-		},
-	}))
+func CreateNotifier(config *config.Config, redis *redis.Client) notifier.Notifier {
+	switch config.GetResetNotifier() {
+	case "mail":
+		return notifier.CreateMailNotifier(redis, config.GetMailKey(), config.GetMailLifetime())
+	case "log", "":
+		return notifier.CreateLogNotifier(config.GetResetFile())
+	}
 
-	return app
+	log.Warn().Msg("Unknown notifier '" + config.GetResetNotifier() + "', falling back to log.")
+
+	return notifier.CreateLogNotifier(config.GetResetFile())
 }
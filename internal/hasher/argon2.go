@@ -0,0 +1,132 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// OWASP recommended baseline for argon2id, memory in KiB.
+const (
+	DefaultArgon2Memory      = 64 * 1024
+	DefaultArgon2Iterations  = 3
+	DefaultArgon2Parallelism = 2
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+type argon2HasherImpl struct {
+	params argon2Params
+}
+
+func (hasher argon2HasherImpl) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	params := hasher.params
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (hasher argon2HasherImpl) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2(encoded)
+
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, candidate) == 1, nil
+}
+
+func (hasher argon2HasherImpl) Supports(encoded string) bool {
+	return prefixed(encoded, "$argon2id$")
+}
+
+func (hasher argon2HasherImpl) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2(encoded)
+
+	return err != nil || params != hasher.params
+}
+
+// Decode $argon2id$v=19$m=65536,t=3,p=2$salt$key
+func decodeArgon2(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	var version int
+
+	parts := strings.Split(encoded, "$")
+
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+
+	if err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+
+	if err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	return params, salt, key, nil
+}
+
+func CreateArgon2Hasher(memory, iterations uint32, parallelism uint8) Hasher {
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+
+	if iterations == 0 {
+		iterations = DefaultArgon2Iterations
+	}
+
+	if parallelism == 0 {
+		parallelism = DefaultArgon2Parallelism
+	}
+
+	return argon2HasherImpl{
+		params: argon2Params{
+			memory:      memory,
+			iterations:  iterations,
+			parallelism: parallelism,
+		},
+	}
+}
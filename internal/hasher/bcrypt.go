@@ -0,0 +1,49 @@
+package hasher
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+type bcryptHasherImpl struct {
+	cost int
+}
+
+func (hasher bcryptHasherImpl) Hash(password string) (string, error) {
+	encrypted, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(encrypted), nil
+}
+
+func (hasher bcryptHasherImpl) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (hasher bcryptHasherImpl) Supports(encoded string) bool {
+	return prefixed(encoded, "$2a$", "$2b$", "$2y$")
+}
+
+func (hasher bcryptHasherImpl) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err != nil || cost != hasher.cost
+}
+
+func CreateBcryptHasher(cost int) Hasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+
+	return bcryptHasherImpl{cost: cost}
+}
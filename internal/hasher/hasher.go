@@ -0,0 +1,89 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+// Password hashing algorithm, hashes carry their identifier (PHC string format) so several can coexist.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+
+	// Whether the encoded hash belongs to this hasher.
+	Supports(encoded string) bool
+
+	// Whether the encoded hash was produced with outdated parameters.
+	NeedsRehash(encoded string) bool
+}
+
+// Hashes with the preferred algorithm while still verifying every known format.
+type multiHasherImpl struct {
+	preferred Hasher
+	known     []Hasher
+}
+
+func (hasher multiHasherImpl) Hash(password string) (string, error) {
+	return hasher.preferred.Hash(password)
+}
+
+func (hasher multiHasherImpl) Verify(password, encoded string) (bool, error) {
+	for _, known := range hasher.known {
+		if known.Supports(encoded) {
+			return known.Verify(password, encoded)
+		}
+	}
+
+	return false, ErrUnknownFormat
+}
+
+func (hasher multiHasherImpl) Supports(encoded string) bool {
+	for _, known := range hasher.known {
+		if known.Supports(encoded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (hasher multiHasherImpl) NeedsRehash(encoded string) bool {
+	if !hasher.preferred.Supports(encoded) {
+		return true
+	}
+
+	return hasher.preferred.NeedsRehash(encoded)
+}
+
+func CreateMultiHasher(preferred Hasher, known ...Hasher) Hasher {
+	return multiHasherImpl{
+		preferred: preferred,
+		known:     append([]Hasher{preferred}, known...),
+	}
+}
+
+var current Hasher = CreateMultiHasher(
+	CreateBcryptHasher(DefaultBcryptCost),
+	CreateArgon2Hasher(DefaultArgon2Memory, DefaultArgon2Iterations, DefaultArgon2Parallelism),
+)
+
+// Hasher used by authentications, replaced on startup by the configured one.
+func Default() Hasher {
+	return current
+}
+
+func SetDefault(hasher Hasher) {
+	current = hasher
+}
+
+func prefixed(encoded string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters, the defaults would make every case take a while.
+func testHashers() map[string]Hasher {
+	return map[string]Hasher{
+		"bcrypt": CreateBcryptHasher(bcrypt.MinCost),
+		"argon2": CreateArgon2Hasher(1024, 1, 1),
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, hasher := range testHashers() {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := hasher.Hash("correct horse")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !hasher.Supports(encoded) {
+				t.Errorf("hasher does not support its own hash %s", encoded)
+			}
+
+			if hasher.NeedsRehash(encoded) {
+				t.Errorf("fresh hash %s needs rehash", encoded)
+			}
+
+			tests := []struct {
+				password string
+				matches  bool
+			}{
+				{"correct horse", true},
+				{"correct horse ", false},
+				{"Correct horse", false},
+				{"", false},
+			}
+
+			for _, test := range tests {
+				matches, err := hasher.Verify(test.password, encoded)
+
+				if err != nil {
+					t.Fatalf("Verify(%q) returned %v", test.password, err)
+				}
+
+				if matches != test.matches {
+					t.Errorf("Verify(%q) = %t, want %t", test.password, matches, test.matches)
+				}
+			}
+		})
+	}
+}
+
+func TestArgon2Format(t *testing.T) {
+	encoded, err := CreateArgon2Hasher(1024, 1, 1).Hash("password")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("hash %s is not in PHC format", encoded)
+	}
+
+	malformed := []string{
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+	}
+
+	for _, encoded := range malformed {
+		if _, err := CreateArgon2Hasher(1024, 1, 1).Verify("password", encoded); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Verify on %s returned %v, want ErrUnknownFormat", encoded, err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, _ := CreateBcryptHasher(bcrypt.MinCost).Hash("password")
+	argon2Hash, _ := CreateArgon2Hasher(1024, 1, 1).Hash("password")
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		rehash  bool
+	}{
+		{"bcrypt same cost", CreateBcryptHasher(bcrypt.MinCost), bcryptHash, false},
+		{"bcrypt higher cost", CreateBcryptHasher(bcrypt.MinCost + 1), bcryptHash, true},
+		{"argon2 same params", CreateArgon2Hasher(1024, 1, 1), argon2Hash, false},
+		{"argon2 more memory", CreateArgon2Hasher(2048, 1, 1), argon2Hash, true},
+		{"argon2 more iterations", CreateArgon2Hasher(1024, 2, 1), argon2Hash, true},
+		{"preferring argon2 over bcrypt", CreateMultiHasher(CreateArgon2Hasher(1024, 1, 1), CreateBcryptHasher(bcrypt.MinCost)), bcryptHash, true},
+		{"preferring bcrypt over argon2", CreateMultiHasher(CreateBcryptHasher(bcrypt.MinCost), CreateArgon2Hasher(1024, 1, 1)), bcryptHash, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rehash := test.hasher.NeedsRehash(test.encoded); rehash != test.rehash {
+				t.Errorf("NeedsRehash = %t, want %t", rehash, test.rehash)
+			}
+		})
+	}
+}
+
+func TestMultiHasher(t *testing.T) {
+	legacy := CreateBcryptHasher(bcrypt.MinCost)
+	multi := CreateMultiHasher(CreateArgon2Hasher(1024, 1, 1), legacy)
+
+	legacyHash, _ := legacy.Hash("password")
+
+	// Hashes of older algorithms keep verifying until they are rehashed.
+	if matches, err := multi.Verify("password", legacyHash); err != nil || !matches {
+		t.Errorf("Verify on bcrypt hash = %t, %v, want true", matches, err)
+	}
+
+	encoded, err := multi.Hash("password")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		t.Errorf("hash %s was not produced by the preferred hasher", encoded)
+	}
+
+	if _, err := multi.Verify("password", "plain"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Verify on unknown format returned %v, want ErrUnknownFormat", err)
+	}
+
+	if multi.Supports("plain") {
+		t.Error("multi hasher supports unknown format")
+	}
+}
@@ -11,36 +11,47 @@ type AccountImpl struct {
 
 	AccountType AccountType `json:"accountType" gorm:"column:account_type;type:varchar(16);not null"`
 
+	// Unique id proven against the session server, empty for cracked accounts.
+	PremiumId string `json:"premium_id" gorm:"column:premium_id;type:char(36);not null;default:''"`
+
 	Name string `json:"name" gorm:"type:varchar(16);not null;column:username"`
-	Cash int32  `json:"cash" gorm:"type:bigint;not null;default:0"`
+	Cash int64  `json:"cash" gorm:"type:bigint;not null;default:0"`
 
 	GroupSet    []GroupInfo `json:"group_set" gorm:"foreignkey:User;references:UUID"`
 	MetadataSet MetadataSet `json:"metadata_set" gorm:"foreignkey:User;references:UUID"`
+	Wallets     []Wallet    `json:"wallets" gorm:"foreignkey:User;references:UUID"`
+
+	// Login credentials sharing the unique id, the foreign key keeps them one-to-one.
+	Authentication *AuthenticationImpl `json:"-" gorm:"foreignkey:UUID;references:UUID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 
 	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 func (account AccountImpl) GetUniqueId() string {
-	return account.GetUniqueId()
+	return account.UUID
 }
 
-func (account AccountImpl) GetCash() int32 {
+func (account AccountImpl) GetCash() int64 {
 	return account.Cash
 }
 
-func (account AccountImpl) AddCash(amount int32) {
+func (account AccountImpl) AddCash(amount int64) {
 	account.Cash += amount
 }
 
-func (account AccountImpl) SetCash(cash int32) {
+func (account AccountImpl) SetCash(cash int64) {
 	account.Cash = cash
 }
 
-func (account AccountImpl) TakeCash(amount int32) {
+func (account AccountImpl) TakeCash(amount int64) {
 	account.Cash -= amount
 }
 
+func (account AccountImpl) GetWallets() []Wallet {
+	return account.Wallets
+}
+
 func (account AccountImpl) GetMetadataSet() MetadataSet {
 	return account.MetadataSet
 }
@@ -49,6 +60,10 @@ func (account AccountImpl) GetAccountType() AccountType {
 	return account.AccountType
 }
 
+func (account AccountImpl) GetPremiumId() string {
+	return account.PremiumId
+}
+
 func (account AccountImpl) GetGroupSet() []GroupInfo {
 	return account.GroupSet
 }
@@ -57,17 +72,18 @@ func (account AccountImpl) AddGroup(group GroupInfo) {
 	account.GroupSet = append(account.GroupSet, group)
 }
 
-func (account AccountImpl) RemoveGroup(group GroupType) {
+func (account AccountImpl) RemoveGroup(group GroupType, context GroupContext) {
 	for i, g := range account.GroupSet {
-		if g.Group == group {
+		if g.Group == group && g.Context.String() == context.String() {
 			account.GroupSet = append(account.GroupSet[:i], account.GroupSet[i+1:]...)
 		}
 	}
 }
 
-func (account AccountImpl) HasGroupSet(group GroupType) bool {
+// Whether the account holds the group on exactly the context, grants on other contexts are distinct.
+func (account AccountImpl) HasGroupSet(group GroupType, context GroupContext) bool {
 	for _, g := range account.GroupSet {
-		if g.Group == group {
+		if g.Group == group && g.Context.String() == context.String() {
 			return true
 		}
 	}
@@ -107,14 +123,15 @@ func CreateGroupInfo(unique, author string, group GroupType, expireAt, createdAt
 		User:  unique,
 		Group: group,
 
-		Author: author,
+		Author:  author,
+		Context: GroupContext{},
 
 		ExpireAt:  expireAt,
 		CreatedAt: createdAt,
 	}
 }
 
-func CreateAccount(unique, name string, cash int32, accountType AccountType, metadataSet MetadataSet, groupInfos []GroupInfo, createdAt, updatedAt time.Time) Account {
+func CreateAccount(unique, name string, cash int64, accountType AccountType, metadataSet MetadataSet, groupInfos []GroupInfo, createdAt, updatedAt time.Time) Account {
 	return AccountImpl{
 		UUIDData: UUIDData{
 			UUID: unique,
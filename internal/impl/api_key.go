@@ -0,0 +1,152 @@
+package impl
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+type APIKeyImpl struct {
+	UUIDData
+
+	Name string `json:"name" gorm:"column:name;type:varchar(64);not null;uniqueIndex"`
+	Hash string `json:"-" gorm:"column:hash;type:char(64);not null;uniqueIndex"`
+
+	// Keys with a signing secret only accept HMAC signed requests.
+	SigningSecret string `json:"-" gorm:"column:signing_secret;type:char(64);not null;default:''"`
+
+	// Comma separated, kept flat so keys stay a single row.
+	Scopes    string `json:"scopes" gorm:"column:scopes;type:varchar(512);not null"`
+	Addresses string `json:"addresses" gorm:"column:addresses;type:varchar(512);not null;default:''"`
+
+	Revoked bool `json:"revoked" gorm:"column:revoked;type:boolean;not null;default:false"`
+
+	ExpireAt  *time.Time `json:"expire_at" gorm:"column:expire_at;type:timestamp NULL"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (key APIKeyImpl) GetUniqueId() string {
+	return key.UUID
+}
+
+func (key APIKeyImpl) GetName() string {
+	return key.Name
+}
+
+func (key APIKeyImpl) GetSigningSecret() string {
+	return key.SigningSecret
+}
+
+func (key APIKeyImpl) RequiresSigning() bool {
+	return len(key.SigningSecret) > 0
+}
+
+func (key APIKeyImpl) GetScopes() []Scope {
+	scopes := []Scope{}
+
+	for _, scope := range splitList(key.Scopes) {
+		scopes = append(scopes, Scope(scope))
+	}
+
+	return scopes
+}
+
+func (key APIKeyImpl) HasScope(scope Scope) bool {
+	for _, owned := range key.GetScopes() {
+		if owned == scope || owned == ALL_SCOPES {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (key APIKeyImpl) GetAddresses() []string {
+	return splitList(key.Addresses)
+}
+
+// Empty allow-lists accept any address, entries may be single addresses or CIDR ranges.
+func (key APIKeyImpl) AllowsAddress(address string) bool {
+	allowed := key.GetAddresses()
+
+	if len(allowed) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(address)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		if other := net.ParseIP(entry); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (key APIKeyImpl) GetExpireAt() *time.Time {
+	return key.ExpireAt
+}
+
+func (key APIKeyImpl) IsExpired() bool {
+	return key.ExpireAt != nil && time.Now().After(*key.ExpireAt)
+}
+
+func (key APIKeyImpl) IsRevoked() bool {
+	return key.Revoked
+}
+
+func (key APIKeyImpl) GetCreatedAt() time.Time {
+	return key.CreatedAt
+}
+
+func splitList(value string) []string {
+	result := []string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) > 0 {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
+
+func CreateAPIKey(unique, name, hash, signingSecret string, scopes []Scope, addresses []string, expireAt *time.Time) APIKey {
+	values := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		values = append(values, string(scope))
+	}
+
+	return APIKeyImpl{
+		UUIDData: UUIDData{
+			UUID: unique,
+		},
+
+		Name: name,
+		Hash: hash,
+
+		SigningSecret: signingSecret,
+
+		Scopes:    strings.Join(values, ","),
+		Addresses: strings.Join(addresses, ","),
+
+		ExpireAt:  expireAt,
+		CreatedAt: time.Now(),
+	}
+}
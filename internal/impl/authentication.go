@@ -5,22 +5,28 @@ import (
 
 	. "github.com/luiz-otavio/galax/pkg/data"
 
+	"github.com/luiz-otavio/galax/internal/hasher"
+
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Login credentials of an account, sharing its unique id and name one-to-one.
 type AuthenticationImpl struct {
 	UUIDData
 
 	Username string `json:"username" gorm:"type:varchar(16);not null;column:username"`
-	Password string `json:"password" gorm:"type:char(60);not null;column:password"`
+	Password string `json:"password" gorm:"type:varchar(255);not null;column:password"`
+
+	TOTPSecret   string `json:"-" gorm:"type:varchar(64);not null;default:'';column:totp_secret"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"type:boolean;not null;default:false;column:totp_enabled"`
+	TOTPLastStep int64  `json:"-" gorm:"type:bigint;not null;default:0;column:totp_last_step"`
 
 	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 func (authentication AuthenticationImpl) GetUniqueId() string {
-	return authentication.GetUniqueId()
+	return authentication.UUID
 }
 
 func (authentication AuthenticationImpl) GetUsername() string {
@@ -39,41 +45,47 @@ func (authentication AuthenticationImpl) GetUpdatedAt() time.Time {
 	return authentication.UpdatedAt
 }
 
-func (authentication AuthenticationImpl) CheckPassword(password string) bool {
-	encrypted, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to encrypt password")
-		return false
-	}
+func (authentication AuthenticationImpl) HasTwoFactor() bool {
+	return authentication.TOTPEnabled
+}
 
-	err = bcrypt.CompareHashAndPassword(
-		[]byte(authentication.Password),
-		encrypted,
-	)
+func (authentication AuthenticationImpl) CheckPassword(password string) bool {
+	matches, err := hasher.Default().Verify(password, authentication.Password)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to compare password")
-		return false
 	}
 
-	return err == nil
+	return matches
 }
 
-func (authentication AuthenticationImpl) UpdatePassword(password string) {
-	encrypted, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// Whether the stored hash uses another algorithm or outdated parameters.
+func (authentication AuthenticationImpl) NeedsRehash() bool {
+	return hasher.Default().NeedsRehash(authentication.Password)
+}
+
+func (authentication *AuthenticationImpl) UpdatePassword(password string) {
+	encrypted, err := hasher.Default().Hash(password)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to encrypt password")
 		return
 	}
 
-	authentication.Password = string(encrypted)
+	authentication.Password = encrypted
+}
+
+type RecoveryCodeImpl struct {
+	User string `json:"-" gorm:"column:user;type:char(36);not null;index"`
+	Hash string `json:"-" gorm:"column:hash;type:char(64);primaryKey"`
+
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
 type LoginImpl struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Address  string `json:"address"`
 }
 
 func (loginRequest LoginImpl) GetUsername() string {
@@ -84,6 +96,10 @@ func (loginRequest LoginImpl) GetPassword() string {
 	return loginRequest.Password
 }
 
+func (loginRequest LoginImpl) GetAddress() string {
+	return loginRequest.Address
+}
+
 func CreateAuthentication(username string, password string) Authentication {
 	authentication := &AuthenticationImpl{
 		Username: username,
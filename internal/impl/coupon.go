@@ -0,0 +1,153 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+// Gift code granting cash and/or a timed group, the code itself is only stored hashed.
+type CouponImpl struct {
+	UUIDData
+
+	Hash  string `json:"-" gorm:"column:hash;type:char(64);not null;uniqueIndex"`
+	Batch string `json:"batch" gorm:"column:batch;type:varchar(64);not null;default:'';index"`
+
+	Currency string `json:"currency" gorm:"column:currency;type:varchar(32);not null;default:''"`
+	Cash     int64  `json:"cash" gorm:"column:cash;type:bigint;not null;default:0"`
+
+	// Seconds the group lasts, extending a grant the account already has.
+	Group    GroupType `json:"group" gorm:"column:role;type:varchar(18);not null;default:''"`
+	Duration int64     `json:"duration" gorm:"column:duration;type:bigint;not null;default:0"`
+
+	// Zero means unlimited.
+	MaxRedemptions int64 `json:"max_redemptions" gorm:"column:max_redemptions;type:bigint;not null;default:0"`
+	PerAccount     int64 `json:"per_account" gorm:"column:per_account;type:bigint;not null;default:0"`
+	Redemptions    int64 `json:"redemptions" gorm:"column:redemptions;type:bigint;not null;default:0"`
+
+	Author string `json:"author" gorm:"column:author;type:varchar(64);not null;default:''"`
+
+	ExpireAt  *time.Time `json:"expire_at" gorm:"column:expire_at;type:timestamp NULL"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (coupon CouponImpl) GetUniqueId() string {
+	return coupon.UUID
+}
+
+func (coupon CouponImpl) GetBatch() string {
+	return coupon.Batch
+}
+
+func (coupon CouponImpl) GetCurrency() string {
+	return coupon.Currency
+}
+
+func (coupon CouponImpl) GetCash() int64 {
+	return coupon.Cash
+}
+
+func (coupon CouponImpl) GetGroup() GroupType {
+	return coupon.Group
+}
+
+func (coupon CouponImpl) GetDuration() time.Duration {
+	return time.Duration(coupon.Duration) * time.Second
+}
+
+func (coupon CouponImpl) GetMaxRedemptions() int64 {
+	return coupon.MaxRedemptions
+}
+
+func (coupon CouponImpl) GetPerAccount() int64 {
+	return coupon.PerAccount
+}
+
+func (coupon CouponImpl) GetRedemptions() int64 {
+	return coupon.Redemptions
+}
+
+func (coupon CouponImpl) GetAuthor() string {
+	return coupon.Author
+}
+
+func (coupon CouponImpl) GetExpireAt() *time.Time {
+	return coupon.ExpireAt
+}
+
+func (coupon CouponImpl) GetCreatedAt() time.Time {
+	return coupon.CreatedAt
+}
+
+func (coupon CouponImpl) IsExpired() bool {
+	return coupon.ExpireAt != nil && time.Now().After(*coupon.ExpireAt)
+}
+
+// Who redeemed a coupon and what it granted.
+type RedemptionImpl struct {
+	ID uint64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	Coupon string `json:"coupon" gorm:"column:coupon;type:char(36);not null;index"`
+	User   string `json:"user" gorm:"column:user;type:char(36);not null;index"`
+
+	Currency string `json:"currency" gorm:"column:currency;type:varchar(32);not null;default:''"`
+	Cash     int64  `json:"cash" gorm:"column:cash;type:bigint;not null;default:0"`
+
+	Group    GroupType  `json:"group" gorm:"column:role;type:varchar(18);not null;default:''"`
+	ExpireAt *time.Time `json:"expire_at" gorm:"column:expire_at;type:timestamp NULL"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (redemption RedemptionImpl) GetCoupon() string {
+	return redemption.Coupon
+}
+
+func (redemption RedemptionImpl) GetUser() string {
+	return redemption.User
+}
+
+func (redemption RedemptionImpl) GetCurrency() string {
+	return redemption.Currency
+}
+
+func (redemption RedemptionImpl) GetCash() int64 {
+	return redemption.Cash
+}
+
+func (redemption RedemptionImpl) GetGroup() GroupType {
+	return redemption.Group
+}
+
+func (redemption RedemptionImpl) GetExpireAt() *time.Time {
+	return redemption.ExpireAt
+}
+
+func (redemption RedemptionImpl) GetCreatedAt() time.Time {
+	return redemption.CreatedAt
+}
+
+func CreateCoupon(unique, hash, batch, currency string, cash int64, group GroupType, duration time.Duration, maxRedemptions, perAccount int64, author string, expireAt *time.Time) Coupon {
+	return CouponImpl{
+		UUIDData: UUIDData{
+			UUID: unique,
+		},
+
+		Hash:  hash,
+		Batch: batch,
+
+		Currency: currency,
+		Cash:     cash,
+
+		Group:    group,
+		Duration: int64(duration / time.Second),
+
+		MaxRedemptions: maxRedemptions,
+		PerAccount:     perAccount,
+
+		Author: author,
+
+		ExpireAt:  expireAt,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,29 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+type CurrencyImpl struct {
+	Name string `json:"name" gorm:"column:name;type:varchar(32);primaryKey"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (currency CurrencyImpl) GetName() string {
+	return currency.Name
+}
+
+func (currency CurrencyImpl) GetCreatedAt() time.Time {
+	return currency.CreatedAt
+}
+
+func CreateCurrency(name string) Currency {
+	return CurrencyImpl{
+		Name: name,
+
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,84 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+type GroupImpl struct {
+	Name GroupType `json:"name" gorm:"column:name;type:varchar(18);primaryKey"`
+
+	Prefix string `json:"prefix" gorm:"column:prefix;type:varchar(32);not null;default:''"`
+	Color  string `json:"color" gorm:"column:color;type:varchar(16);not null;default:''"`
+	Weight int    `json:"weight" gorm:"column:weight;type:int;not null;default:0"`
+
+	// Group given to accounts holding no other one, only a single group is the default.
+	Default bool `json:"default" gorm:"column:is_default;type:boolean;not null;default:false"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (GroupImpl) TableName() string {
+	return "groups"
+}
+
+func (group GroupImpl) GetName() GroupType {
+	return group.Name
+}
+
+func (group GroupImpl) GetPrefix() string {
+	return group.Prefix
+}
+
+func (group GroupImpl) GetColor() string {
+	return group.Color
+}
+
+func (group GroupImpl) GetWeight() int {
+	return group.Weight
+}
+
+func (group GroupImpl) IsDefault() bool {
+	return group.Default
+}
+
+func (group GroupImpl) GetCreatedAt() time.Time {
+	return group.CreatedAt
+}
+
+func (group GroupImpl) GetUpdatedAt() time.Time {
+	return group.UpdatedAt
+}
+
+func CreateGroup(name GroupType, prefix, color string, weight int, isDefault bool) Group {
+	return GroupImpl{
+		Name: name,
+
+		Prefix: prefix,
+		Color:  color,
+		Weight: weight,
+
+		Default: isDefault,
+
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Permission node of a group, negated with a leading "-" and matching every child with a trailing "*".
+type GroupPermissionImpl struct {
+	Group GroupType `json:"group" gorm:"column:role;type:varchar(18);primaryKey"`
+	Node  string    `json:"node" gorm:"column:node;type:varchar(128);primaryKey"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// Group inheriting every node of the parent, its own nodes taking precedence.
+type GroupParentImpl struct {
+	Group  GroupType `json:"group" gorm:"column:role;type:varchar(18);primaryKey"`
+	Parent GroupType `json:"parent" gorm:"column:parent;type:varchar(18);primaryKey"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,95 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+// Purchase fulfilled from a store transaction, keeping what was granted so it can be revoked.
+type PurchaseImpl struct {
+	UUIDData
+
+	Transaction string `json:"transaction" gorm:"column:transaction;type:varchar(64);not null;uniqueIndex"`
+	User        string `json:"user" gorm:"column:user;type:char(36);not null;index"`
+	Package     string `json:"package" gorm:"column:package;type:varchar(64);not null"`
+
+	Currency string `json:"currency" gorm:"column:currency;type:varchar(32);not null;default:''"`
+	Cash     int64  `json:"cash" gorm:"column:cash;type:bigint;not null;default:0"`
+
+	Group    GroupType `json:"group" gorm:"column:role;type:varchar(18);not null;default:''"`
+	Duration int64     `json:"duration" gorm:"column:duration;type:bigint;not null;default:0"`
+
+	// Either "fulfilled", "chargeback" or "refund".
+	Status string `json:"status" gorm:"column:status;type:varchar(16);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (purchase PurchaseImpl) GetUniqueId() string {
+	return purchase.UUID
+}
+
+func (purchase PurchaseImpl) GetTransaction() string {
+	return purchase.Transaction
+}
+
+func (purchase PurchaseImpl) GetUser() string {
+	return purchase.User
+}
+
+func (purchase PurchaseImpl) GetPackage() string {
+	return purchase.Package
+}
+
+func (purchase PurchaseImpl) GetCurrency() string {
+	return purchase.Currency
+}
+
+func (purchase PurchaseImpl) GetCash() int64 {
+	return purchase.Cash
+}
+
+func (purchase PurchaseImpl) GetGroup() GroupType {
+	return purchase.Group
+}
+
+func (purchase PurchaseImpl) GetDuration() time.Duration {
+	return time.Duration(purchase.Duration) * time.Second
+}
+
+func (purchase PurchaseImpl) GetStatus() string {
+	return purchase.Status
+}
+
+func (purchase PurchaseImpl) GetCreatedAt() time.Time {
+	return purchase.CreatedAt
+}
+
+func (purchase PurchaseImpl) GetUpdatedAt() time.Time {
+	return purchase.UpdatedAt
+}
+
+func CreatePurchase(unique, transaction, user, pkg, currency string, cash int64, group GroupType, duration time.Duration) Purchase {
+	return PurchaseImpl{
+		UUIDData: UUIDData{
+			UUID: unique,
+		},
+
+		Transaction: transaction,
+		User:        user,
+		Package:     pkg,
+
+		Currency: currency,
+		Cash:     cash,
+
+		Group:    group,
+		Duration: int64(duration / time.Second),
+
+		Status: "fulfilled",
+
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,71 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+type SessionImpl struct {
+	UUIDData
+
+	User string `json:"user" gorm:"column:user;type:char(36);not null;index"`
+
+	Address string `json:"address" gorm:"column:address;type:varchar(45);not null"`
+	Agent   string `json:"agent" gorm:"column:agent;type:varchar(255);not null"`
+
+	Revoked bool `json:"revoked" gorm:"column:revoked;type:boolean;not null;default:false"`
+
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"column:last_seen_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	ExpireAt   time.Time `json:"expire_at" gorm:"column:expire_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (session SessionImpl) GetUniqueId() string {
+	return session.UUID
+}
+
+func (session SessionImpl) GetUser() string {
+	return session.User
+}
+
+func (session SessionImpl) GetAddress() string {
+	return session.Address
+}
+
+func (session SessionImpl) GetAgent() string {
+	return session.Agent
+}
+
+func (session SessionImpl) GetCreatedAt() time.Time {
+	return session.CreatedAt
+}
+
+func (session SessionImpl) GetLastSeenAt() time.Time {
+	return session.LastSeenAt
+}
+
+func (session SessionImpl) GetExpireAt() time.Time {
+	return session.ExpireAt
+}
+
+func (session SessionImpl) IsRevoked() bool {
+	return session.Revoked
+}
+
+func CreateSession(unique, user, address, agent string, createdAt, expireAt time.Time) Session {
+	return SessionImpl{
+		UUIDData: UUIDData{
+			UUID: unique,
+		},
+
+		User: user,
+
+		Address: address,
+		Agent:   agent,
+
+		CreatedAt:  createdAt,
+		LastSeenAt: createdAt,
+		ExpireAt:   expireAt,
+	}
+}
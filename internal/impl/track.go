@@ -0,0 +1,45 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+type TrackImpl struct {
+	Name   string      `json:"name" gorm:"column:name;type:varchar(32);primaryKey"`
+	Groups GroupLadder `json:"groups" gorm:"column:ladder;type:varchar(255);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (TrackImpl) TableName() string {
+	return "tracks"
+}
+
+func (track TrackImpl) GetName() string {
+	return track.Name
+}
+
+func (track TrackImpl) GetGroups() GroupLadder {
+	return track.Groups
+}
+
+func (track TrackImpl) GetCreatedAt() time.Time {
+	return track.CreatedAt
+}
+
+func (track TrackImpl) GetUpdatedAt() time.Time {
+	return track.UpdatedAt
+}
+
+func CreateTrack(name string, groups GroupLadder) Track {
+	return TrackImpl{
+		Name:   name,
+		Groups: groups,
+
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
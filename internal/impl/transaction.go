@@ -0,0 +1,93 @@
+package impl
+
+import (
+	"time"
+
+	. "github.com/luiz-otavio/galax/pkg/data"
+)
+
+// Append-only ledger entry, the account cash always equals the sum of its entries.
+type TransactionImpl struct {
+	ID uint64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	User     string `json:"-" gorm:"column:user;type:char(36);not null;index:idx_transaction_user_currency"`
+	Currency string `json:"currency" gorm:"column:currency;type:varchar(32);not null;default:'';index:idx_transaction_user_currency"`
+
+	Amount  int64 `json:"amount" gorm:"column:amount;type:bigint;not null"`
+	Balance int64 `json:"balance" gorm:"column:balance;type:bigint;not null"`
+
+	Reason string `json:"reason" gorm:"column:reason;type:varchar(64);not null"`
+	Source string `json:"source" gorm:"column:source;type:varchar(64);not null"`
+	Actor  string `json:"actor" gorm:"column:actor;type:varchar(64);not null;default:''"`
+
+	// Other side of a transfer, empty for every other change.
+	Counterparty string `json:"counterparty" gorm:"column:counterparty;type:char(36);not null;default:''"`
+
+	IdempotencyKey string `json:"idempotency_key" gorm:"column:idempotency_key;type:varchar(64);not null;default:'';index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (transaction TransactionImpl) GetId() uint64 {
+	return transaction.ID
+}
+
+func (transaction TransactionImpl) GetUser() string {
+	return transaction.User
+}
+
+func (transaction TransactionImpl) GetCurrency() string {
+	return transaction.Currency
+}
+
+func (transaction TransactionImpl) GetAmount() int64 {
+	return transaction.Amount
+}
+
+func (transaction TransactionImpl) GetBalance() int64 {
+	return transaction.Balance
+}
+
+func (transaction TransactionImpl) GetReason() string {
+	return transaction.Reason
+}
+
+func (transaction TransactionImpl) GetSource() string {
+	return transaction.Source
+}
+
+func (transaction TransactionImpl) GetActor() string {
+	return transaction.Actor
+}
+
+func (transaction TransactionImpl) GetCounterparty() string {
+	return transaction.Counterparty
+}
+
+func (transaction TransactionImpl) GetIdempotencyKey() string {
+	return transaction.IdempotencyKey
+}
+
+func (transaction TransactionImpl) GetCreatedAt() time.Time {
+	return transaction.CreatedAt
+}
+
+func CreateTransaction(user, currency string, amount, balance int64, reason, source, actor, counterparty, idempotencyKey string) Transaction {
+	return TransactionImpl{
+		User:     user,
+		Currency: currency,
+
+		Amount:  amount,
+		Balance: balance,
+
+		Reason: reason,
+		Source: source,
+		Actor:  actor,
+
+		Counterparty: counterparty,
+
+		IdempotencyKey: idempotencyKey,
+
+		CreatedAt: time.Now(),
+	}
+}
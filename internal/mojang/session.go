@@ -0,0 +1,90 @@
+package mojang
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The session server answered, but the player did not join with that server id.
+var ErrNotVerified = errors.New("player has not joined with the given server id")
+
+type Profile struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Checks premium logins against a Mojang compatible hasJoined endpoint.
+type SessionServer interface {
+	HasJoined(username, serverId, address string) (Profile, error)
+}
+
+type sessionServerImpl struct {
+	url    string
+	client *http.Client
+}
+
+func (server sessionServerImpl) HasJoined(username, serverId, address string) (Profile, error) {
+	var profile Profile
+
+	target, err := url.Parse(server.url)
+
+	if err != nil {
+		return profile, err
+	}
+
+	query := target.Query()
+
+	query.Set("username", username)
+	query.Set("serverId", serverId)
+
+	if len(address) > 0 {
+		query.Set("ip", address)
+	}
+
+	target.RawQuery = query.Encode()
+
+	response, err := server.client.Get(target.String())
+
+	if err != nil {
+		return profile, err
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound, http.StatusForbidden:
+		return profile, ErrNotVerified
+	default:
+		return profile, errors.New("unexpected session server status: " + response.Status)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		return profile, err
+	}
+
+	// Mojang answers with undashed ids, normalize them to the stored format.
+	id, err := uuid.Parse(profile.Id)
+
+	if err != nil {
+		return profile, err
+	}
+
+	profile.Id = id.String()
+
+	return profile, nil
+}
+
+func CreateSessionServer(url string, timeout time.Duration) SessionServer {
+	return sessionServerImpl{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
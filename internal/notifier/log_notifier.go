@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Local notifier, prints every message and optionally appends it to a file as JSON lines.
+type logNotifierImpl struct {
+	file string
+
+	lock *sync.Mutex
+}
+
+func (notifier logNotifierImpl) Notify(notification Notification) error {
+	log.Info().
+		Str("user", notification.User).
+		Str("username", notification.Username).
+		Str("subject", notification.Subject).
+		Msg(notification.Body)
+
+	if len(notifier.file) == 0 {
+		return nil
+	}
+
+	line, err := json.Marshal(notification)
+
+	if err != nil {
+		return err
+	}
+
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+
+	file, err := os.OpenFile(notifier.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+
+	return err
+}
+
+func CreateLogNotifier(file string) Notifier {
+	return logNotifierImpl{
+		file: file,
+		lock: &sync.Mutex{},
+	}
+}
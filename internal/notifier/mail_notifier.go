@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// In-game mail, messages wait on a Redis list until a game server polls them.
+type mailNotifierImpl struct {
+	redis *redis.Client
+
+	key      string
+	lifetime time.Duration
+}
+
+func (notifier mailNotifierImpl) Notify(notification Notification) error {
+	message, err := json.Marshal(notification)
+
+	if err != nil {
+		return err
+	}
+
+	context := context.Background()
+
+	_, err = notifier.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		p.RPush(context, notifier.key+"-"+notification.User, message)
+		p.Expire(context, notifier.key+"-"+notification.User, notifier.lifetime)
+
+		return nil
+	})
+
+	return err
+}
+
+func (notifier mailNotifierImpl) Poll(user string) ([]Notification, error) {
+	context := context.Background()
+
+	var messages *redis.StringSliceCmd
+
+	_, err := notifier.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		messages = p.LRange(context, notifier.key+"-"+user, 0, -1)
+		p.Del(context, notifier.key+"-"+user)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]Notification, 0, len(messages.Val()))
+
+	for _, message := range messages.Val() {
+		var notification Notification
+
+		if err := json.Unmarshal([]byte(message), &notification); err != nil {
+			continue
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func CreateMailNotifier(client *redis.Client, key string, lifetime time.Duration) Mailbox {
+	return mailNotifierImpl{
+		redis: client,
+
+		key:      key,
+		lifetime: lifetime,
+	}
+}
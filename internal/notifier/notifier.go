@@ -0,0 +1,27 @@
+package notifier
+
+import (
+	"time"
+)
+
+type Notification struct {
+	User     string `json:"user"`
+	Username string `json:"username"`
+
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivers messages to players, such as password reset tokens.
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+// Notifiers which keep messages until a game server collects them.
+type Mailbox interface {
+	Notifier
+
+	Poll(user string) ([]Notification, error)
+}
@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"bufio"
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Rules struct {
+	UsernamePattern string
+
+	MinPassword int
+	MaxPassword int
+
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	DenyCommon   bool
+	DenyUsername bool
+}
+
+// Registration rules for usernames and passwords.
+type Policy interface {
+	CheckUsername(username string) []Violation
+	CheckPassword(username, password string) []Violation
+
+	Check(username, password string) []Violation
+}
+
+type policyImpl struct {
+	rules Rules
+
+	username *regexp.Regexp
+	denied   map[string]bool
+}
+
+func (policy policyImpl) CheckUsername(username string) []Violation {
+	violations := []Violation{}
+
+	if !policy.username.MatchString(username) {
+		violations = append(violations, Violation{
+			Field:   "username",
+			Code:    "invalid_username",
+			Message: "Username must match " + policy.username.String(),
+		})
+	}
+
+	return violations
+}
+
+func (policy policyImpl) CheckPassword(username, password string) []Violation {
+	violations := []Violation{}
+	rules := policy.rules
+
+	length := len([]rune(password))
+
+	if length < rules.MinPassword {
+		violations = append(violations, Violation{
+			Field:   "password",
+			Code:    "too_short",
+			Message: "Password is too short",
+		})
+	}
+
+	if rules.MaxPassword > 0 && length > rules.MaxPassword {
+		violations = append(violations, Violation{
+			Field:   "password",
+			Code:    "too_long",
+			Message: "Password is too long",
+		})
+	}
+
+	var lower, upper, digit, symbol bool
+
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):
+			lower = true
+		case unicode.IsUpper(char):
+			upper = true
+		case unicode.IsDigit(char):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := []struct {
+		required bool
+		present  bool
+		code     string
+		message  string
+	}{
+		{rules.RequireLower, lower, "missing_lower", "Password must contain a lowercase letter"},
+		{rules.RequireUpper, upper, "missing_upper", "Password must contain an uppercase letter"},
+		{rules.RequireDigit, digit, "missing_digit", "Password must contain a digit"},
+		{rules.RequireSymbol, symbol, "missing_symbol", "Password must contain a symbol"},
+	}
+
+	for _, class := range classes {
+		if class.required && !class.present {
+			violations = append(violations, Violation{
+				Field:   "password",
+				Code:    class.code,
+				Message: class.message,
+			})
+		}
+	}
+
+	if rules.DenyCommon && policy.denied[strings.ToLower(password)] {
+		violations = append(violations, Violation{
+			Field:   "password",
+			Code:    "common_password",
+			Message: "Password is too common",
+		})
+	}
+
+	if rules.DenyUsername && len(username) > 0 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, Violation{
+			Field:   "password",
+			Code:    "contains_username",
+			Message: "Password must not contain the username",
+		})
+	}
+
+	return violations
+}
+
+func (policy policyImpl) Check(username, password string) []Violation {
+	return append(policy.CheckUsername(username), policy.CheckPassword(username, password)...)
+}
+
+func CreatePolicy(rules Rules) (Policy, error) {
+	if len(rules.UsernamePattern) == 0 {
+		rules.UsernamePattern = "^[A-Za-z0-9_]{3,16}$"
+	}
+
+	username, err := regexp.Compile(rules.UsernamePattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	denied := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswords))
+
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 {
+			denied[strings.ToLower(line)] = true
+		}
+	}
+
+	return policyImpl{
+		rules: rules,
+
+		username: username,
+		denied:   denied,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func codesOf(violations []Violation) []string {
+	codes := []string{}
+
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+
+	return codes
+}
+
+func TestCheckUsername(t *testing.T) {
+	policy, err := CreatePolicy(Rules{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"Notch", true},
+		{"player_123", true},
+		{"abc", true},
+		{"ab", false},
+		{"seventeen_letters", false},
+		{"with space", false},
+		{"dash-name", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if valid := len(policy.CheckUsername(test.username)) == 0; valid != test.valid {
+			t.Errorf("CheckUsername(%q) valid = %t, want %t", test.username, valid, test.valid)
+		}
+	}
+}
+
+func TestCustomUsernamePattern(t *testing.T) {
+	if _, err := CreatePolicy(Rules{UsernamePattern: "["}); err == nil {
+		t.Error("CreatePolicy accepted an invalid username pattern")
+	}
+
+	policy, err := CreatePolicy(Rules{UsernamePattern: "^[a-z]+$"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if violations := policy.CheckUsername("Notch"); len(violations) != 1 || violations[0].Code != "invalid_username" {
+		t.Errorf("CheckUsername with custom pattern = %v, want invalid_username", violations)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	strict := Rules{
+		MinPassword: 8,
+		MaxPassword: 16,
+
+		RequireLower:  true,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+
+		DenyCommon:   true,
+		DenyUsername: true,
+	}
+
+	tests := []struct {
+		name     string
+		rules    Rules
+		username string
+		password string
+		codes    []string
+	}{
+		{"strong password", strict, "notch", "C0rrect-Horse", []string{}},
+		{"too short", strict, "notch", "Aa1!", []string{"too_short"}},
+		{"too long", strict, "notch", "C0rrect-Horse-Battery", []string{"too_long"}},
+		{"length counts runes", Rules{MinPassword: 4}, "notch", "ãéíõ", []string{}},
+		{"missing every class", strict, "notch", "        ", []string{"missing_lower", "missing_upper", "missing_digit"}},
+		{"missing symbol", strict, "notch", "C0rrectHorse", []string{"missing_symbol"}},
+		{"common password", Rules{DenyCommon: true}, "notch", "Password", []string{"common_password"}},
+		{"common password allowed", Rules{}, "notch", "password", []string{}},
+		{"contains username", Rules{DenyUsername: true}, "Notch", "mynotch!", []string{"contains_username"}},
+		{"empty username", Rules{DenyUsername: true}, "", "anything", []string{}},
+		{"no maximum", Rules{MinPassword: 1}, "notch", "a very long password that keeps going on", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := CreatePolicy(test.rules)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if codes := codesOf(policy.CheckPassword(test.username, test.password)); !reflect.DeepEqual(codes, test.codes) {
+				t.Errorf("CheckPassword(%q) = %v, want %v", test.password, codes, test.codes)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := CreatePolicy(Rules{MinPassword: 8})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := policy.Check("ab", "short")
+
+	if codes := codesOf(violations); !reflect.DeepEqual(codes, []string{"invalid_username", "too_short"}) {
+		t.Errorf("Check = %v, want invalid_username and too_short", codes)
+	}
+
+	if violations[0].Field != "username" || violations[1].Field != "password" {
+		t.Errorf("Check fields = %s, %s, want username, password", violations[0].Field, violations[1].Field)
+	}
+}
@@ -0,0 +1,417 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reason of the ledger entries written when the cache wins.
+const ReconcileReason = "reconcile"
+
+// The database balance changed after it was compared, so the cached one is stale rather than drifted.
+var ErrBalanceMoved = errors.New("balance moved since it was compared, left for the next run")
+
+// Which copy is kept when both disagree, NONE only reports the differences.
+type Authority string
+
+const (
+	NONE     Authority = "none"
+	DATABASE Authority = "db"
+	CACHE    Authority = "cache"
+)
+
+func ParseAuthority(value string) (Authority, error) {
+	switch strings.ToLower(value) {
+	case "none", "":
+		return NONE, nil
+	case "db", "database":
+		return DATABASE, nil
+	case "cache", "redis":
+		return CACHE, nil
+	}
+
+	return NONE, errors.New("unknown authority: " + value)
+}
+
+// A field of an account holding different values on cache and database, nil when absent from one side.
+type Difference struct {
+	User  string `json:"user"`
+	Field string `json:"field"`
+
+	Cache    interface{} `json:"cache"`
+	Database interface{} `json:"database"`
+}
+
+type Report struct {
+	Authority Authority `json:"authority"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	Checked  int `json:"checked"`
+	Drifted  int `json:"drifted"`
+	Repaired int `json:"repaired"`
+
+	// Cached accounts which do not exist on database.
+	Missing []string `json:"missing"`
+
+	Differences []Difference `json:"differences"`
+	Errors      []string     `json:"errors"`
+}
+
+type Reconciler interface {
+	// Compare every cached account against database, repairing the drifted ones unless the authority is NONE.
+	Reconcile(authority Authority) (Report, error)
+
+	// Reconcile on every interval in background until shutdown.
+	Initialize(interval time.Duration, authority Authority)
+	Shutdown()
+}
+
+type reconcilerImpl struct {
+	db     *gorm.DB
+	cache  repository.RedisRepository
+	ledger repository.LedgerRepository
+	config *config.Config
+
+	stop chan struct{}
+}
+
+func (reconciler *reconcilerImpl) Reconcile(authority Authority) (Report, error) {
+	report := Report{
+		Authority: authority,
+		StartedAt: time.Now(),
+
+		Missing:     []string{},
+		Differences: []Difference{},
+		Errors:      []string{},
+	}
+
+	accounts, err := reconciler.cache.CachedAccounts()
+
+	if err != nil {
+		return report, err
+	}
+
+	for _, unique := range accounts {
+		cached := reconciler.cache.LoadAccount(unique)
+
+		// Expired between the scan and the load, nothing left to compare.
+		if cached == nil {
+			continue
+		}
+
+		report.Checked++
+
+		var stored AccountImpl
+
+		err := reconciler.db.Preload(clause.Associations).Where("unique_id = ?", unique).First(&stored).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			report.Drifted++
+			report.Missing = append(report.Missing, unique)
+
+			// An account cannot be rebuilt from cache, it is only dropped when the database wins.
+			if authority == DATABASE {
+				reconciler.cache.InvalidateAccount(unique)
+				report.Repaired++
+			}
+
+			continue
+		}
+
+		if err != nil {
+			report.Errors = append(report.Errors, unique+": "+err.Error())
+			continue
+		}
+
+		differences := reconciler.Compare(cached, stored)
+
+		if len(differences) == 0 {
+			continue
+		}
+
+		report.Drifted++
+		report.Differences = append(report.Differences, differences...)
+
+		if authority == NONE {
+			continue
+		}
+
+		if err := reconciler.Repair(authority, cached, stored, differences); err != nil {
+			report.Errors = append(report.Errors, unique+": "+err.Error())
+			continue
+		}
+
+		report.Repaired++
+	}
+
+	report.FinishedAt = time.Now()
+
+	return report, nil
+}
+
+// Differences between the cached and stored copies of an account, on cash, wallets, groups and metadata.
+func (reconciler *reconcilerImpl) Compare(cached, stored data.Account) []Difference {
+	unique := stored.GetUniqueId()
+	differences := []Difference{}
+
+	if cached.GetCash() != stored.GetCash() {
+		differences = append(differences, Difference{unique, "cash", cached.GetCash(), stored.GetCash()})
+	}
+
+	cachedWallets, storedWallets := walletsOf(cached), walletsOf(stored)
+
+	for currency, balance := range storedWallets {
+		if target, ok := cachedWallets[currency]; !ok {
+			differences = append(differences, Difference{unique, "wallets." + currency, nil, balance})
+		} else if target != balance {
+			differences = append(differences, Difference{unique, "wallets." + currency, target, balance})
+		}
+	}
+
+	for currency, balance := range cachedWallets {
+		if _, ok := storedWallets[currency]; !ok {
+			differences = append(differences, Difference{unique, "wallets." + currency, balance, nil})
+		}
+	}
+
+	cachedGroups, storedGroups := groupsOf(cached), groupsOf(stored)
+
+	for key, group := range storedGroups {
+		target, ok := cachedGroups[key]
+
+		if !ok {
+			differences = append(differences, Difference{unique, "groups." + key, nil, group})
+			continue
+		}
+
+		// Redis keeps times in seconds, anything below that is not drift.
+		if target.Author != group.Author ||
+			target.Context.String() != group.Context.String() ||
+			target.ExpireAt.Unix() != group.ExpireAt.Unix() ||
+			target.CreatedAt.Unix() != group.CreatedAt.Unix() {
+			differences = append(differences, Difference{unique, "groups." + key, target, group})
+		}
+	}
+
+	for key, group := range cachedGroups {
+		if _, ok := storedGroups[key]; !ok {
+			differences = append(differences, Difference{unique, "groups." + key, group, nil})
+		}
+	}
+
+	cachedMetadata, storedMetadata := reflect.ValueOf(cached.GetMetadataSet()), reflect.ValueOf(stored.GetMetadataSet())
+
+	for i := 0; i < cachedMetadata.NumField(); i++ {
+		name := strings.Split(cachedMetadata.Type().Field(i).Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if target, value := cachedMetadata.Field(i).Interface(), storedMetadata.Field(i).Interface(); target != value {
+			differences = append(differences, Difference{unique, "metadata." + name, target, value})
+		}
+	}
+
+	return differences
+}
+
+// Make both copies agree, either reloading the cache from database or writing the cached values to it.
+func (reconciler *reconcilerImpl) Repair(authority Authority, cached, stored data.Account, differences []Difference) error {
+	unique := stored.GetUniqueId()
+
+	if authority == DATABASE {
+		reconciler.cache.InvalidateAccount(unique)
+		reconciler.cache.SaveAccount(stored)
+
+		return nil
+	}
+
+	entry := repository.LedgerEntry{
+		Reason: ReconcileReason,
+		Source: ReconcileReason,
+	}
+
+	moved := false
+
+	err := reconciler.db.Transaction(func(tx *gorm.DB) error {
+		cachedWallets := walletsOf(cached)
+		cachedWallets[reconciler.config.GetDefaultCurrency()] = cached.GetCash()
+
+		storedWallets := walletsOf(stored)
+		storedWallets[reconciler.config.GetDefaultCurrency()] = stored.GetCash()
+
+		cachedGroups := groupsOf(cached)
+
+		metadata := false
+
+		for _, difference := range differences {
+			switch {
+			case difference.Field == "cash" || strings.HasPrefix(difference.Field, "wallets."):
+				currency := strings.TrimPrefix(difference.Field, "wallets.")
+
+				if difference.Field == "cash" {
+					currency = reconciler.config.GetDefaultCurrency()
+				}
+
+				balance, ok := cachedWallets[currency]
+
+				// Balances missing from cache were never cached, database keeps them.
+				if !ok {
+					continue
+				}
+
+				// Written through the ledger, so the balance remains the sum of its entries.
+				// The locked balance must still be the compared one, otherwise a mutation committed
+				// since then would be overwritten with the older cached value.
+				_, err := reconciler.ledger.ApplyWith(tx, unique, currency, func(current int64) (int64, error) {
+					if current != storedWallets[currency] {
+						return current, ErrBalanceMoved
+					}
+
+					return balance, nil
+				}, entry)
+
+				if errors.Is(err, ErrBalanceMoved) {
+					moved = true
+					continue
+				}
+
+				if err != nil {
+					return err
+				}
+			case strings.HasPrefix(difference.Field, "groups."):
+				group, ok := cachedGroups[strings.TrimPrefix(difference.Field, "groups.")]
+
+				// Grants absent from cache may have failed to load or expired there, database keeps them.
+				if !ok {
+					continue
+				}
+
+				group.User = unique
+
+				if err := tx.Where("user = ? AND role = ? AND context = ?", unique, group.Group, group.Context.String()).Delete(&data.GroupInfo{}).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Create(&group).Error; err != nil {
+					return err
+				}
+			case strings.HasPrefix(difference.Field, "metadata."):
+				metadata = true
+			}
+		}
+
+		if metadata {
+			target := cached.GetMetadataSet()
+			target.User = unique
+
+			if err := tx.Where("user = ?", unique).Delete(&data.MetadataSet{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Create(&target).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if moved {
+		return ErrBalanceMoved
+	}
+
+	return nil
+}
+
+func (reconciler *reconcilerImpl) Initialize(interval time.Duration, authority Authority) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-reconciler.stop:
+				return
+			case <-ticker.C:
+			}
+
+			report, err := reconciler.Reconcile(authority)
+
+			if err != nil {
+				log.Error().Err(err).Msg("Cannot reconcile cached accounts.")
+				continue
+			}
+
+			if report.Drifted == 0 && len(report.Errors) == 0 {
+				log.Info().Int("checked", report.Checked).Msg("Cache and database are reconciled.")
+				continue
+			}
+
+			encoded, err := json.Marshal(report)
+
+			if err != nil {
+				log.Error().Err(err).Msg("Cannot encode reconciliation report.")
+				continue
+			}
+
+			log.Warn().RawJSON("report", encoded).Msg("Found drift between cache and database.")
+		}
+	}()
+}
+
+func (reconciler *reconcilerImpl) Shutdown() {
+	close(reconciler.stop)
+}
+
+func walletsOf(account data.Account) map[string]int64 {
+	wallets := map[string]int64{}
+
+	for _, wallet := range account.GetWallets() {
+		wallets[wallet.Currency] = wallet.Balance
+	}
+
+	return wallets
+}
+
+// Grants keyed by group and context, as the same group can be granted on several contexts.
+func groupsOf(account data.Account) map[string]data.GroupInfo {
+	groups := map[string]data.GroupInfo{}
+
+	for _, group := range account.GetGroupSet() {
+		groups[group.Key()] = group
+	}
+
+	return groups
+}
+
+func CreateReconciler(db *gorm.DB, cache repository.RedisRepository, ledger repository.LedgerRepository, config *config.Config) Reconciler {
+	return &reconcilerImpl{
+		db:     db,
+		cache:  cache,
+		ledger: ledger,
+		config: config,
+
+		stop: make(chan struct{}),
+	}
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Prefix of every generated key, makes leaked keys easy to spot.
+const apiKeyPrefix = "glx_"
+
+type APIKeyRepository interface {
+	// Create a key returning its plain value and signing secret when requested,
+	// neither can be shown again.
+	CreateKey(name string, scopes []data.Scope, addresses []string, expireAt *time.Time, signing bool) (string, string, data.APIKey, error)
+
+	FindKey(plain string) data.APIKey
+	FindKeyByName(name string) data.APIKey
+	ListKeys() ([]data.APIKey, error)
+
+	RevokeKey(name string) error
+
+	// Remember a nonce for the key, returning false when it was already used.
+	UseNonce(name, nonce string, lifetime time.Duration) bool
+}
+
+type apiKeyRepositoryImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	config *config.Config
+}
+
+// Hash and signing secret are hidden from JSON, so they are carried explicitly on cache.
+type cachedAPIKey struct {
+	Key APIKeyImpl `json:"key"`
+
+	Hash          string `json:"hash"`
+	SigningSecret string `json:"signing_secret"`
+}
+
+func (repository apiKeyRepositoryImpl) CreateKey(name string, scopes []data.Scope, addresses []string, expireAt *time.Time, signing bool) (string, string, data.APIKey, error) {
+	plain, err := randomHex(32)
+
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	plain = apiKeyPrefix + plain
+
+	var secret string
+
+	if signing {
+		if secret, err = randomHex(32); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	key := CreateAPIKey(uuid.NewString(), name, hashToken(plain), secret, scopes, addresses, expireAt).(APIKeyImpl)
+
+	if err := repository.db.Create(&key).Error; err != nil {
+		return "", "", nil, err
+	}
+
+	return plain, secret, key, nil
+}
+
+func (repository apiKeyRepositoryImpl) FindKey(plain string) data.APIKey {
+	return repository.find("hash", hashToken(plain))
+}
+
+func (repository apiKeyRepositoryImpl) FindKeyByName(name string) data.APIKey {
+	return repository.find("name", name)
+}
+
+func (repository apiKeyRepositoryImpl) ListKeys() ([]data.APIKey, error) {
+	var keys []APIKeyImpl
+
+	if err := repository.db.Order("created_at ASC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]data.APIKey, 0, len(keys))
+
+	for _, key := range keys {
+		result = append(result, key)
+	}
+
+	return result, nil
+}
+
+func (repository apiKeyRepositoryImpl) RevokeKey(name string) error {
+	var key APIKeyImpl
+
+	if err := repository.db.Where("name = ?", name).First(&key).Error; err != nil {
+		return err
+	}
+
+	if err := repository.db.Model(&key).Update("revoked", true).Error; err != nil {
+		return err
+	}
+
+	return repository.redis.Del(context.Background(), repository.key("hash", key.Hash), repository.key("name", key.Name)).Err()
+}
+
+func (repository apiKeyRepositoryImpl) UseNonce(name, nonce string, lifetime time.Duration) bool {
+	fresh, err := repository.redis.SetNX(context.Background(), repository.key("nonce-"+name, nonce), 1, lifetime).Result()
+
+	// Fail closed, a replay could mint money.
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot store nonce for API key: " + name)
+		return false
+	}
+
+	return fresh
+}
+
+// Every request resolves its key, keep them shortly on cache to spare the database.
+func (repository apiKeyRepositoryImpl) find(column, value string) data.APIKey {
+	context := context.Background()
+
+	if encoded, err := repository.redis.Get(context, repository.key(column, value)).Bytes(); err == nil {
+		var cached cachedAPIKey
+
+		if err := json.Unmarshal(encoded, &cached); err == nil {
+			cached.Key.Hash = cached.Hash
+			cached.Key.SigningSecret = cached.SigningSecret
+
+			return cached.Key
+		}
+	}
+
+	var key APIKeyImpl
+
+	if err := repository.db.Where(column+" = ?", value).First(&key).Error; err != nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(cachedAPIKey{
+		Key: key,
+
+		Hash:          key.Hash,
+		SigningSecret: key.SigningSecret,
+	})
+
+	if err == nil {
+		err = repository.redis.Set(context, repository.key(column, value), encoded, repository.config.GetAPIKeyCache()).Err()
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot cache API key: " + key.GetName())
+	}
+
+	return key
+}
+
+func (repository apiKeyRepositoryImpl) key(kind, value string) string {
+	return repository.config.GetAPIKeyCacheKey() + "-" + kind + "-" + value
+}
+
+func randomHex(size int) (string, error) {
+	raw := make([]byte, size)
+
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+func CreateAPIKeyRepository(db *gorm.DB, client *redis.Client, config *config.Config) APIKeyRepository {
+	return apiKeyRepositoryImpl{
+		db:     db,
+		redis:  client,
+		config: config,
+	}
+}
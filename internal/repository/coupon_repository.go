@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reason of the ledger entries paid by coupons.
+const CouponReason = "coupon"
+
+// Letters and digits which cannot be mistaken for each other when typed from a gift card.
+const couponAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	ErrCouponExists    = errors.New("coupon already exists")
+	ErrCouponNotFound  = errors.New("coupon not found")
+	ErrCouponExpired   = errors.New("coupon expired")
+	ErrCouponExhausted = errors.New("coupon has no redemptions left")
+	ErrCouponLimit     = errors.New("account reached the coupon limit")
+)
+
+type CouponRepository interface {
+	// Mint count copies of the template returning their codes, which cannot be shown again.
+	// A custom code may be given when minting a single coupon.
+	MintCoupons(template data.Coupon, code string, count int) ([]string, error)
+	ListCoupons(batch string) ([]data.Coupon, error)
+
+	// Redeem the code for the user, paying its cash through the ledger and granting its group in the same transaction.
+	Redeem(code, user string, entry LedgerEntry) (data.Redemption, data.Transaction, *data.GroupInfo, error)
+}
+
+type couponRepositoryImpl struct {
+	db     *gorm.DB
+	ledger LedgerRepository
+}
+
+func (repository couponRepositoryImpl) MintCoupons(template data.Coupon, code string, count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	coupons := make([]CouponImpl, 0, count)
+
+	for i := 0; i < count; i++ {
+		target := NormalizeCode(code)
+
+		if len(target) == 0 {
+			generated, err := generateCode()
+
+			if err != nil {
+				return nil, err
+			}
+
+			target = generated
+		} else if repository.db.Where("hash = ?", hashToken(target)).First(&CouponImpl{}).Error == nil {
+			return nil, ErrCouponExists
+		}
+
+		coupon := template.(CouponImpl)
+
+		coupon.UUID = uuid.NewString()
+		coupon.Hash = hashToken(target)
+
+		codes = append(codes, FormatCode(target))
+		coupons = append(coupons, coupon)
+	}
+
+	if err := repository.db.CreateInBatches(&coupons, 100).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (repository couponRepositoryImpl) ListCoupons(batch string) ([]data.Coupon, error) {
+	var coupons []CouponImpl
+
+	query := repository.db.Order("created_at DESC")
+
+	if len(batch) > 0 {
+		query = query.Where("batch = ?", batch)
+	}
+
+	if err := query.Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]data.Coupon, 0, len(coupons))
+
+	for _, coupon := range coupons {
+		result = append(result, coupon)
+	}
+
+	return result, nil
+}
+
+func (repository couponRepositoryImpl) Redeem(code, user string, entry LedgerEntry) (data.Redemption, data.Transaction, *data.GroupInfo, error) {
+	var redemption RedemptionImpl
+	var transaction data.Transaction
+	var group *data.GroupInfo
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("unique_id").Where("unique_id = ?", user).First(&AccountImpl{}).Error; err != nil {
+			return err
+		}
+
+		var coupon CouponImpl
+
+		// Locking the coupon serializes redemptions, so the counters below cannot be raced.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("hash = ?", hashToken(NormalizeCode(code))).
+			First(&coupon).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if coupon.IsExpired() {
+			return ErrCouponExpired
+		}
+
+		if coupon.MaxRedemptions > 0 && coupon.Redemptions >= coupon.MaxRedemptions {
+			return ErrCouponExhausted
+		}
+
+		if coupon.PerAccount > 0 {
+			var redeemed int64
+
+			if err := tx.Model(&RedemptionImpl{}).Where("coupon = ? AND user = ?", coupon.UUID, user).Count(&redeemed).Error; err != nil {
+				return err
+			}
+
+			if redeemed >= coupon.PerAccount {
+				return ErrCouponLimit
+			}
+		}
+
+		redemption = RedemptionImpl{
+			Coupon: coupon.UUID,
+			User:   user,
+
+			Currency: coupon.Currency,
+			Cash:     coupon.Cash,
+
+			Group: coupon.Group,
+
+			CreatedAt: time.Now(),
+		}
+
+		if coupon.Cash != 0 {
+			entry.Reason = CouponReason
+			entry.Actor = coupon.UUID
+
+			transaction, err = repository.ledger.ApplyWith(tx, user, coupon.Currency, func(balance int64) (int64, error) {
+				return AddBalance(balance, coupon.Cash)
+			}, entry)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(coupon.Group) > 0 {
+			granted, err := GrantGroup(tx, user, coupon.UUID, coupon.Group, coupon.GetDuration())
+
+			if err != nil {
+				return err
+			}
+
+			group = &granted
+			redemption.ExpireAt = &granted.ExpireAt
+		}
+
+		if err := tx.Model(&coupon).Update("redemptions", gorm.Expr("redemptions + 1")).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&redemption).Error
+	})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return redemption, transaction, group, nil
+}
+
+// Grant the group for the duration, extending the grant without context when the account already holds it.
+// Grants limited to a context are left apart, they were given for that context only.
+func GrantGroup(tx *gorm.DB, user, author string, role data.GroupType, duration time.Duration) (data.GroupInfo, error) {
+	now := time.Now()
+
+	var group data.GroupInfo
+
+	err := tx.Where("user = ? AND role = ? AND context = ''", user, role).First(&group).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		group = CreateGroupInfo(user, author, role, now.Add(duration), now)
+
+		return group, tx.Create(&group).Error
+	}
+
+	if err != nil {
+		return group, err
+	}
+
+	start := group.ExpireAt
+
+	if start.Before(now) {
+		start = now
+	}
+
+	group.ExpireAt = start.Add(duration)
+
+	err = tx.Model(&data.GroupInfo{}).
+		Where("user = ? AND role = ? AND context = ''", user, role).
+		Update("expire_at", group.ExpireAt).Error
+
+	return group, err
+}
+
+// Uppercase the code and drop the separators people type along with it.
+func NormalizeCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Split the code in groups of four, as printed on gift cards.
+func FormatCode(code string) string {
+	var builder strings.Builder
+
+	for i, char := range code {
+		if i > 0 && i%4 == 0 {
+			builder.WriteByte('-')
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+func generateCode() (string, error) {
+	raw := make([]byte, 12)
+
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	for i := range raw {
+		raw[i] = couponAlphabet[int(raw[i])%len(couponAlphabet)]
+	}
+
+	return string(raw), nil
+}
+
+func CreateCouponRepository(db *gorm.DB, ledger LedgerRepository) CouponRepository {
+	return couponRepositoryImpl{
+		db:     db,
+		ledger: ledger,
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var currencyPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+var ErrInvalidCurrency = errors.New("currency must be 1 to 32 lowercase letters, digits or underscores")
+
+type CurrencyRepository interface {
+	ListCurrencies() ([]data.Currency, error)
+	HasCurrency(name string) bool
+
+	// Create the currency, doing nothing when it already exists.
+	CreateCurrency(name string) (data.Currency, error)
+
+	// Create the currencies from config, moving ledger entries written before currencies to the default one.
+	Seed() error
+}
+
+type currencyRepositoryImpl struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func (repository currencyRepositoryImpl) ListCurrencies() ([]data.Currency, error) {
+	var currencies []CurrencyImpl
+
+	if err := repository.db.Order("name ASC").Find(&currencies).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]data.Currency, 0, len(currencies))
+
+	for _, currency := range currencies {
+		result = append(result, currency)
+	}
+
+	return result, nil
+}
+
+func (repository currencyRepositoryImpl) HasCurrency(name string) bool {
+	if name == repository.config.GetDefaultCurrency() {
+		return true
+	}
+
+	return repository.db.Where("name = ?", name).First(&CurrencyImpl{}).Error == nil
+}
+
+func (repository currencyRepositoryImpl) CreateCurrency(name string) (data.Currency, error) {
+	if !currencyPattern.MatchString(name) {
+		return nil, ErrInvalidCurrency
+	}
+
+	currency := CreateCurrency(name).(CurrencyImpl)
+
+	if err := repository.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&currency).Error; err != nil {
+		return nil, err
+	}
+
+	return currency, nil
+}
+
+func (repository currencyRepositoryImpl) Seed() error {
+	names := append([]string{repository.config.GetDefaultCurrency()}, repository.config.GetCurrencies()...)
+
+	for _, name := range names {
+		if _, err := repository.CreateCurrency(name); err != nil {
+			return err
+		}
+	}
+
+	return repository.db.Model(&TransactionImpl{}).
+		Where("currency = ?", "").
+		Update("currency", repository.config.GetDefaultCurrency()).Error
+}
+
+func CreateCurrencyRepository(db *gorm.DB, config *config.Config) CurrencyRepository {
+	return currencyRepositoryImpl{
+		db:     db,
+		config: config,
+	}
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var groupPattern = regexp.MustCompile(`^[A-Z0-9_]{1,18}$`)
+
+var (
+	ErrInvalidGroup  = errors.New("group name must be 1 to 18 letters, digits or underscores")
+	ErrGroupNotFound = errors.New("unknown group type")
+	ErrGroupExists   = errors.New("group already exists")
+	ErrGroupInUse    = errors.New("group is still granted to accounts")
+)
+
+// Weights of the groups which used to be hardcoded, seeded on first start.
+var legacyGroups = map[data.GroupType]int{
+	data.OWNER:     100,
+	data.ADMIN:     90,
+	data.MODERATOR: 80,
+	data.HELPER:    70,
+	data.YOUTUBER:  60,
+	data.STREAMER:  50,
+	data.ELITE:     40,
+	data.MVP:       30,
+	data.VIP:       20,
+	data.PATRON:    10,
+	data.DEFAULT:   0,
+}
+
+type GroupRepository interface {
+	// Every group on the registry, from the highest weight.
+	ListGroups() ([]data.Group, error)
+
+	// Find the group by name in any case, ErrGroupNotFound when it is not on the registry.
+	FindGroup(name string) (data.Group, error)
+
+	CreateGroup(group data.Group) (data.Group, error)
+	UpdateGroup(group data.Group) (data.Group, error)
+
+	// Delete the group, ErrGroupInUse while accounts still hold it.
+	DeleteGroup(name string) error
+
+	// Create the legacy groups on an empty registry, along with any group already granted to accounts.
+	Seed() error
+
+	// The heaviest non-expired grant applying on the context, the default group when none does.
+	PrimaryGroup(grants []data.GroupInfo, context data.GroupContext) (data.GroupType, error)
+}
+
+type groupRepositoryImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	config *config.Config
+}
+
+func (repository groupRepositoryImpl) ListGroups() ([]data.Group, error) {
+	groups, err := repository.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]data.Group, 0, len(groups))
+
+	for _, group := range groups {
+		result = append(result, group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].GetWeight() != result[j].GetWeight() {
+			return result[i].GetWeight() > result[j].GetWeight()
+		}
+
+		return result[i].GetName() < result[j].GetName()
+	})
+
+	return result, nil
+}
+
+func (repository groupRepositoryImpl) FindGroup(name string) (data.Group, error) {
+	groups, err := repository.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	group, ok := groups[data.GroupType(strings.ToUpper(name))]
+
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	return group, nil
+}
+
+func (repository groupRepositoryImpl) CreateGroup(group data.Group) (data.Group, error) {
+	target := group.(GroupImpl)
+	target.Name = data.GroupType(strings.ToUpper(string(target.Name)))
+
+	if !groupPattern.MatchString(string(target.Name)) {
+		return nil, ErrInvalidGroup
+	}
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Where("name = ?", target.Name).First(&GroupImpl{}).Error == nil {
+			return ErrGroupExists
+		}
+
+		if err := repository.clearDefault(tx, target); err != nil {
+			return err
+		}
+
+		return tx.Create(&target).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	repository.invalidate()
+
+	return target, nil
+}
+
+func (repository groupRepositoryImpl) UpdateGroup(group data.Group) (data.Group, error) {
+	target := group.(GroupImpl)
+	target.UpdatedAt = time.Now()
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ?", target.Name).First(&GroupImpl{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGroupNotFound
+			}
+
+			return err
+		}
+
+		if err := repository.clearDefault(tx, target); err != nil {
+			return err
+		}
+
+		return tx.Model(&GroupImpl{}).Where("name = ?", target.Name).Updates(map[string]interface{}{
+			"prefix":     target.Prefix,
+			"color":      target.Color,
+			"weight":     target.Weight,
+			"is_default": target.Default,
+			"updated_at": target.UpdatedAt,
+		}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	repository.invalidate()
+
+	return target, nil
+}
+
+func (repository groupRepositoryImpl) DeleteGroup(name string) error {
+	name = strings.ToUpper(name)
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		var granted int64
+
+		if err := tx.Model(&data.GroupInfo{}).Where("role = ?", name).Count(&granted).Error; err != nil {
+			return err
+		}
+
+		if granted > 0 {
+			return ErrGroupInUse
+		}
+
+		result := tx.Where("name = ?", name).Delete(&GroupImpl{})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrGroupNotFound
+		}
+
+		if err := tx.Where("role = ?", name).Delete(&GroupPermissionImpl{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("role = ? OR parent = ?", name, name).Delete(&GroupParentImpl{}).Error
+	})
+
+	if err != nil {
+		return err
+	}
+
+	repository.invalidate()
+
+	return BumpPermissions(repository.redis, repository.config)
+}
+
+func (repository groupRepositoryImpl) Seed() error {
+	var total int64
+
+	if err := repository.db.Model(&GroupImpl{}).Count(&total).Error; err != nil {
+		return err
+	}
+
+	groups := []GroupImpl{}
+
+	if total == 0 {
+		for name, weight := range legacyGroups {
+			groups = append(groups, CreateGroup(name, "", "", weight, name == data.DEFAULT).(GroupImpl))
+		}
+	}
+
+	// Accounts may hold groups which were never on the registry, they must keep resolving.
+	var granted []string
+
+	if err := repository.db.Model(&data.GroupInfo{}).Distinct("role").Pluck("role", &granted).Error; err != nil {
+		return err
+	}
+
+	for _, role := range granted {
+		groups = append(groups, CreateGroup(data.GroupType(role), "", "", 0, false).(GroupImpl))
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	if err := repository.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&groups).Error; err != nil {
+		return err
+	}
+
+	repository.invalidate()
+
+	return nil
+}
+
+func (repository groupRepositoryImpl) PrimaryGroup(grants []data.GroupInfo, context data.GroupContext) (data.GroupType, error) {
+	groups, err := repository.load()
+
+	if err != nil {
+		return data.UNKNOWN, err
+	}
+
+	primary := data.DEFAULT
+
+	for _, group := range groups {
+		if group.Default {
+			primary = group.Name
+		}
+	}
+
+	now := time.Now()
+	weight := groups[primary].Weight
+
+	for _, grant := range grants {
+		if !grant.ExpireAt.After(now) || !grant.Context.Matches(context) {
+			continue
+		}
+
+		// Groups missing from the registry weigh nothing, as they do on permissions.
+		if target := groups[grant.Group].Weight; target > weight {
+			primary, weight = grant.Group, target
+		}
+	}
+
+	return primary, nil
+}
+
+// Only a single group is the default one, the others are cleared when the target takes over.
+func (repository groupRepositoryImpl) clearDefault(tx *gorm.DB, target GroupImpl) error {
+	if !target.Default {
+		return nil
+	}
+
+	return tx.Model(&GroupImpl{}).Where("name <> ? AND is_default = ?", target.Name, true).Update("is_default", false).Error
+}
+
+// Every group by name, from redis when cached. The whole registry is cached at once, it is small and read on every grant.
+func (repository groupRepositoryImpl) load() (map[data.GroupType]GroupImpl, error) {
+	context := context.Background()
+	key := repository.config.GetGroupKey()
+
+	groups := map[data.GroupType]GroupImpl{}
+
+	cached, err := repository.redis.HGetAll(context, key).Result()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot load cached groups, falling back to database.")
+	}
+
+	for name, value := range cached {
+		var group GroupImpl
+
+		if err := json.Unmarshal([]byte(value), &group); err != nil {
+			log.Error().Err(err).Msg("Cannot parse cached group: " + name)
+
+			groups = map[data.GroupType]GroupImpl{}
+			break
+		}
+
+		groups[group.Name] = group
+	}
+
+	if len(groups) > 0 {
+		return groups, nil
+	}
+
+	var stored []GroupImpl
+
+	if err := repository.db.Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	entries := map[string]interface{}{}
+
+	for _, group := range stored {
+		encoded, err := json.Marshal(group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		groups[group.Name] = group
+		entries[string(group.Name)] = encoded
+	}
+
+	if len(entries) == 0 {
+		return groups, nil
+	}
+
+	_, err = repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		p.HSet(context, key, entries)
+		p.Expire(context, key, repository.config.GetExpireInterval())
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot cache groups.")
+	}
+
+	return groups, nil
+}
+
+func (repository groupRepositoryImpl) invalidate() {
+	if err := repository.redis.Del(context.Background(), repository.config.GetGroupKey()).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot invalidate cached groups.")
+	}
+}
+
+func CreateGroupRepository(db *gorm.DB, redis *redis.Client, config *config.Config) GroupRepository {
+	return groupRepositoryImpl{
+		db:     db,
+		redis:  redis,
+		config: config,
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Response stored for an idempotency key, a zero status means the first request is still running.
+type StoredResponse struct {
+	Fingerprint string `json:"fingerprint"`
+
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+type IdempotencyRepository interface {
+	// Reserve the key for a new request, returning false and what is stored when it was already seen.
+	Reserve(key, fingerprint string) (StoredResponse, bool, error)
+
+	Complete(key string, response StoredResponse) error
+
+	// Forget the key, so a failed request can be retried.
+	Release(key string) error
+}
+
+type idempotencyRepositoryImpl struct {
+	redis  *redis.Client
+	config *config.Config
+}
+
+func (repository idempotencyRepositoryImpl) Reserve(key, fingerprint string) (StoredResponse, bool, error) {
+	context := context.Background()
+
+	pending, err := json.Marshal(StoredResponse{
+		Fingerprint: fingerprint,
+	})
+
+	if err != nil {
+		return StoredResponse{}, false, err
+	}
+
+	reserved, err := repository.redis.SetNX(context, repository.key(key), pending, repository.config.GetIdempotencyWindow()).Result()
+
+	if err != nil || reserved {
+		return StoredResponse{}, reserved, err
+	}
+
+	encoded, err := repository.redis.Get(context, repository.key(key)).Bytes()
+
+	// Expired in between, reserve it again.
+	if err == redis.Nil {
+		return repository.Reserve(key, fingerprint)
+	}
+
+	if err != nil {
+		return StoredResponse{}, false, err
+	}
+
+	var stored StoredResponse
+
+	if err := json.Unmarshal(encoded, &stored); err != nil {
+		return StoredResponse{}, false, err
+	}
+
+	return stored, false, nil
+}
+
+func (repository idempotencyRepositoryImpl) Complete(key string, response StoredResponse) error {
+	encoded, err := json.Marshal(response)
+
+	if err != nil {
+		return err
+	}
+
+	return repository.redis.Set(context.Background(), repository.key(key), encoded, repository.config.GetIdempotencyWindow()).Err()
+}
+
+func (repository idempotencyRepositoryImpl) Release(key string) error {
+	return repository.redis.Del(context.Background(), repository.key(key)).Err()
+}
+
+func (repository idempotencyRepositoryImpl) key(key string) string {
+	return repository.config.GetIdempotencyKey() + "-" + key
+}
+
+func CreateIdempotencyRepository(client *redis.Client, config *config.Config) IdempotencyRepository {
+	return idempotencyRepositoryImpl{
+		redis:  client,
+		config: config,
+	}
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Position of an account on the leaderboard of a currency, ranks start at one.
+type LeaderboardEntry struct {
+	Rank    int64  `json:"rank"`
+	User    string `json:"unique_id"`
+	Name    string `json:"name"`
+	Balance int64  `json:"balance"`
+}
+
+type LeaderboardRepository interface {
+	// Richest accounts on the currency from the offset, along with the amount of ranked accounts.
+	Top(currency string, offset, limit int64) ([]LeaderboardEntry, int64, error)
+
+	// Position of the user on the currency, false when the user is not ranked.
+	Rank(currency, user string) (LeaderboardEntry, bool, error)
+
+	// Repopulate the leaderboard of the currency from MySQL, returning the amount of ranked accounts.
+	Rebuild(currency string) (int64, error)
+}
+
+type leaderboardRepositoryImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	config *config.Config
+}
+
+func (repository leaderboardRepositoryImpl) Top(currency string, offset, limit int64) ([]LeaderboardEntry, int64, error) {
+	context := context.Background()
+	key := LeaderboardKey(repository.config, currency)
+
+	total, err := repository.redis.ZCard(context, key).Result()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	members, err := repository.redis.ZRevRangeWithScores(context, key, offset, offset+limit-1).Result()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(members))
+
+	for i, member := range members {
+		entries = append(entries, LeaderboardEntry{
+			Rank:    offset + int64(i) + 1,
+			User:    member.Member.(string),
+			Balance: int64(member.Score),
+		})
+	}
+
+	return entries, total, repository.name(entries)
+}
+
+func (repository leaderboardRepositoryImpl) Rank(currency, user string) (LeaderboardEntry, bool, error) {
+	context := context.Background()
+	key := LeaderboardKey(repository.config, currency)
+
+	rank, err := repository.redis.ZRevRank(context, key, user).Result()
+
+	if err == redis.Nil {
+		return LeaderboardEntry{}, false, nil
+	}
+
+	if err != nil {
+		return LeaderboardEntry{}, false, err
+	}
+
+	score, err := repository.redis.ZScore(context, key, user).Result()
+
+	if err != nil {
+		return LeaderboardEntry{}, false, err
+	}
+
+	entries := []LeaderboardEntry{{
+		Rank:    rank + 1,
+		User:    user,
+		Balance: int64(score),
+	}}
+
+	return entries[0], true, repository.name(entries)
+}
+
+func (repository leaderboardRepositoryImpl) Rebuild(currency string) (int64, error) {
+	context := context.Background()
+
+	key := LeaderboardKey(repository.config, currency)
+	staging := key + "-rebuild"
+
+	if err := repository.redis.Del(context, staging).Err(); err != nil {
+		return 0, err
+	}
+
+	var query *gorm.DB
+
+	if currency == repository.config.GetDefaultCurrency() {
+		query = repository.db.Model(&AccountImpl{}).Select("unique_id, cash")
+	} else {
+		query = repository.db.Model(&data.Wallet{}).Select("user, balance").Where("currency = ?", currency)
+	}
+
+	rows, err := query.Rows()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	var total int64
+	members := []*redis.Z{}
+
+	flush := func() error {
+		if len(members) == 0 {
+			return nil
+		}
+
+		err := repository.redis.ZAdd(context, staging, members...).Err()
+		members = members[:0]
+
+		return err
+	}
+
+	for rows.Next() {
+		var user string
+		var balance int64
+
+		if err := rows.Scan(&user, &balance); err != nil {
+			return total, err
+		}
+
+		members = append(members, &redis.Z{Score: float64(balance), Member: user})
+		total++
+
+		if len(members) >= 1000 {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return total, err
+	}
+
+	if err := flush(); err != nil {
+		return total, err
+	}
+
+	if total == 0 {
+		return 0, repository.redis.Del(context, key).Err()
+	}
+
+	// Swapped at once, readers never see a partial leaderboard.
+	return total, repository.redis.Rename(context, staging, key).Err()
+}
+
+// Fill the names of the entries from MySQL, the leaderboard only holds unique ids.
+func (repository leaderboardRepositoryImpl) name(entries []LeaderboardEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	users := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		users = append(users, entry.User)
+	}
+
+	var accounts []AccountImpl
+
+	if err := repository.db.Select("unique_id, username").Where("unique_id IN ?", users).Find(&accounts).Error; err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+
+	for _, account := range accounts {
+		names[account.UUID] = account.Name
+	}
+
+	for i := range entries {
+		entries[i].Name = names[entries[i].User]
+	}
+
+	return nil
+}
+
+// Sorted set ranking the balances of a currency, scores are doubles and exact up to 2^53.
+func LeaderboardKey(config *config.Config, currency string) string {
+	return config.GetLeaderboardKey() + "-" + currency
+}
+
+func CreateLeaderboardRepository(db *gorm.DB, redis *redis.Client, config *config.Config) LeaderboardRepository {
+	return leaderboardRepositoryImpl{
+		db:     db,
+		redis:  redis,
+		config: config,
+	}
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reason of the entry opening the ledger of accounts created before it.
+const OpeningReason = "opening"
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrOutOfBounds       = errors.New("balance out of bounds")
+)
+
+// Describes who changed a balance and why, stored on every ledger entry.
+type LedgerEntry struct {
+	Reason string
+	Source string
+	Actor  string
+
+	Counterparty string
+
+	IdempotencyKey string
+}
+
+type LedgerRepository interface {
+	// Change the balance of the user on the currency and append it to the ledger in a single transaction,
+	// change receives the current balance and returns the new one, which must fit the currency limits.
+	Apply(user, currency string, change func(balance int64) (int64, error), entry LedgerEntry) (data.Transaction, error)
+
+	// Same as Apply, but within a transaction owned by the caller.
+	ApplyWith(tx *gorm.DB, user, currency string, change func(balance int64) (int64, error), entry LedgerEntry) (data.Transaction, error)
+
+	// Move a currency between two accounts in a single transaction, returning the entries of both.
+	Transfer(from, to, currency string, amount int64, entry LedgerEntry) (data.Transaction, data.Transaction, error)
+
+	// Entries of the user on the currency from the newest, along with the total amount of them.
+	History(user, currency string, offset, limit int) ([]data.Transaction, int64, error)
+}
+
+type ledgerRepositoryImpl struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func (repository ledgerRepositoryImpl) Apply(user, currency string, change func(balance int64) (int64, error), entry LedgerEntry) (data.Transaction, error) {
+	var transaction TransactionImpl
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+
+		transaction, err = repository.applyEntry(tx, user, currency, change, entry)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (repository ledgerRepositoryImpl) ApplyWith(tx *gorm.DB, user, currency string, change func(balance int64) (int64, error), entry LedgerEntry) (data.Transaction, error) {
+	transaction, err := repository.applyEntry(tx, user, currency, change, entry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (repository ledgerRepositoryImpl) Transfer(from, to, currency string, amount int64, entry LedgerEntry) (data.Transaction, data.Transaction, error) {
+	var debit, credit TransactionImpl
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		users := []string{from, to}
+
+		// Always lock in the same order, otherwise opposite transfers would deadlock.
+		sort.Strings(users)
+
+		for _, user := range users {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("unique_id").
+				Where("unique_id = ?", user).
+				First(&AccountImpl{}).Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+
+		entry.Counterparty = to
+
+		debit, err = repository.applyEntry(tx, from, currency, func(balance int64) (int64, error) {
+			return SubBalance(balance, amount)
+		}, entry)
+
+		if err != nil {
+			return err
+		}
+
+		entry.Counterparty = from
+
+		credit, err = repository.applyEntry(tx, to, currency, func(balance int64) (int64, error) {
+			return AddBalance(balance, amount)
+		}, entry)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return debit, credit, nil
+}
+
+func (repository ledgerRepositoryImpl) History(user, currency string, offset, limit int) ([]data.Transaction, int64, error) {
+	var total int64
+
+	if err := repository.db.Model(&TransactionImpl{}).Where("user = ? AND currency = ?", user, currency).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []TransactionImpl
+
+	err := repository.db.
+		Where("user = ? AND currency = ?", user, currency).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&transactions).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]data.Transaction, 0, len(transactions))
+
+	for _, transaction := range transactions {
+		result = append(result, transaction)
+	}
+
+	return result, total, nil
+}
+
+// Lock the account row, so concurrent changes are serialized, then write the new balance and its entry.
+// The default currency lives on the account cash, every other one on its wallet.
+func (repository ledgerRepositoryImpl) applyEntry(tx *gorm.DB, user, currency string, change func(balance int64) (int64, error), entry LedgerEntry) (TransactionImpl, error) {
+	var account AccountImpl
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("unique_id", "cash").
+		Where("unique_id = ?", user).
+		First(&account).Error
+
+	if err != nil {
+		return TransactionImpl{}, err
+	}
+
+	current := account.Cash
+	isDefault := currency == repository.config.GetDefaultCurrency()
+
+	if !isDefault {
+		wallet := data.Wallet{
+			User:     user,
+			Currency: currency,
+		}
+
+		// The account lock is held, so nobody else can be creating the same wallet.
+		if err := tx.Where("user = ? AND currency = ?", user, currency).FirstOrCreate(&wallet).Error; err != nil {
+			return TransactionImpl{}, err
+		}
+
+		current = wallet.Balance
+	}
+
+	var opened int64
+
+	if err := tx.Model(&TransactionImpl{}).Where("user = ? AND currency = ?", user, currency).Count(&opened).Error; err != nil {
+		return TransactionImpl{}, err
+	}
+
+	// Accounts older than the ledger open it with their balance, keeping the sum equal to it.
+	if opened == 0 && current != 0 {
+		opening := CreateTransaction(user, currency, current, current, OpeningReason, "", "", "", "").(TransactionImpl)
+
+		if err := tx.Create(&opening).Error; err != nil {
+			return TransactionImpl{}, err
+		}
+	}
+
+	balance, err := change(current)
+
+	if err != nil {
+		return TransactionImpl{}, err
+	}
+
+	floor, ceiling := repository.config.GetCurrencyLimits(currency)
+
+	if balance < floor && balance < current {
+		return TransactionImpl{}, ErrInsufficientFunds
+	}
+
+	if balance < floor || balance > ceiling {
+		return TransactionImpl{}, ErrOutOfBounds
+	}
+
+	delta, err := SubBalance(balance, current)
+
+	if err != nil {
+		return TransactionImpl{}, err
+	}
+
+	transaction := CreateTransaction(
+		user,
+		currency,
+		delta,
+		balance,
+		entry.Reason,
+		entry.Source,
+		entry.Actor,
+		entry.Counterparty,
+		entry.IdempotencyKey,
+	).(TransactionImpl)
+
+	if delta != 0 {
+		// The row is locked already, the condition keeps the bounds even against writers outside the ledger.
+		var result *gorm.DB
+
+		if isDefault {
+			result = tx.Model(&AccountImpl{}).
+				Where("unique_id = ? AND cash + ? BETWEEN ? AND ?", user, delta, floor, ceiling).
+				Update("cash", gorm.Expr("cash + ?", delta))
+		} else {
+			result = tx.Model(&data.Wallet{}).
+				Where("user = ? AND currency = ? AND balance + ? BETWEEN ? AND ?", user, currency, delta, floor, ceiling).
+				Update("balance", gorm.Expr("balance + ?", delta))
+		}
+
+		if result.Error != nil {
+			return TransactionImpl{}, result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return TransactionImpl{}, ErrOutOfBounds
+		}
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return TransactionImpl{}, err
+	}
+
+	return transaction, nil
+}
+
+// Sum which refuses to overflow instead of wrapping around.
+func AddBalance(balance, amount int64) (int64, error) {
+	sum := balance + amount
+
+	if (amount > 0 && sum < balance) || (amount < 0 && sum > balance) {
+		return balance, ErrOutOfBounds
+	}
+
+	return sum, nil
+}
+
+// Difference which refuses to overflow instead of wrapping around.
+func SubBalance(balance, amount int64) (int64, error) {
+	difference := balance - amount
+
+	if (amount > 0 && difference > balance) || (amount < 0 && difference < balance) {
+		return balance, ErrOutOfBounds
+	}
+
+	return difference, nil
+}
+
+func CreateLedgerRepository(db *gorm.DB, config *config.Config) LedgerRepository {
+	return ledgerRepositoryImpl{
+		db:     db,
+		config: config,
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAddBalance(t *testing.T) {
+	tests := []struct {
+		balance, amount int64
+		sum             int64
+		err             error
+	}{
+		{100, 50, 150, nil},
+		{100, -150, -50, nil},
+		{0, 0, 0, nil},
+		{math.MaxInt64 - 1, 1, math.MaxInt64, nil},
+		{math.MaxInt64, 1, math.MaxInt64, ErrOutOfBounds},
+		{1, math.MaxInt64, 1, ErrOutOfBounds},
+		{math.MinInt64 + 1, -1, math.MinInt64, nil},
+		{math.MinInt64, -1, math.MinInt64, ErrOutOfBounds},
+		{math.MaxInt64, math.MinInt64, -1, nil},
+	}
+
+	for _, test := range tests {
+		sum, err := AddBalance(test.balance, test.amount)
+
+		if sum != test.sum || !errors.Is(err, test.err) {
+			t.Errorf("AddBalance(%d, %d) = %d, %v, want %d, %v", test.balance, test.amount, sum, err, test.sum, test.err)
+		}
+	}
+}
+
+func TestSubBalance(t *testing.T) {
+	tests := []struct {
+		balance, amount int64
+		difference      int64
+		err             error
+	}{
+		{100, 50, 50, nil},
+		{100, 150, -50, nil},
+		{100, -50, 150, nil},
+		{0, 0, 0, nil},
+		{math.MinInt64 + 1, 1, math.MinInt64, nil},
+		{math.MinInt64, 1, math.MinInt64, ErrOutOfBounds},
+		{math.MaxInt64, -1, math.MaxInt64, ErrOutOfBounds},
+		{0, math.MinInt64, 0, ErrOutOfBounds},
+		{-1, math.MinInt64, math.MaxInt64, nil},
+	}
+
+	for _, test := range tests {
+		difference, err := SubBalance(test.balance, test.amount)
+
+		if difference != test.difference || !errors.Is(err, test.err) {
+			t.Errorf("SubBalance(%d, %d) = %d, %v, want %d, %v", test.balance, test.amount, difference, err, test.difference, test.err)
+		}
+	}
+}
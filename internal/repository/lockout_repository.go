@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// Shortest lockout, a lock without duration would never expire.
+const minimumLockout = time.Second
+
+type LockoutRepository interface {
+	// Remaining time until the username or address can try again, zero when allowed.
+	// Only the username is locked when the game server gives no player address.
+	Check(username, address string) time.Duration
+
+	Fail(username, address string) time.Duration
+	Succeed(username, address string)
+
+	Clear(username, address string) error
+}
+
+type lockoutRepositoryImpl struct {
+	redis  *redis.Client
+	config *config.Config
+}
+
+func (repository lockoutRepositoryImpl) Check(username, address string) time.Duration {
+	context := context.Background()
+
+	var remaining time.Duration
+
+	for _, key := range repository.keys(username, address) {
+		ttl, err := repository.redis.PTTL(context, key+"-locked").Result()
+
+		// Fail open, Redis being down must not lock everyone out.
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot check lockout for: " + key)
+			continue
+		}
+
+		if ttl > remaining {
+			remaining = ttl
+		}
+	}
+
+	return remaining
+}
+
+func (repository lockoutRepositoryImpl) Fail(username, address string) time.Duration {
+	userLock := repository.increment(repository.key("user", username), repository.config.GetLockoutThreshold())
+
+	var addressLock time.Duration
+
+	if len(address) > 0 {
+		addressLock = repository.increment(repository.key("address", address), repository.config.GetLockoutAddressThreshold())
+	}
+
+	if userLock > 0 {
+		log.Warn().
+			Str("username", username).
+			Str("address", address).
+			Dur("duration", userLock).
+			Msg("Locked out username after repeated failed attempts.")
+	}
+
+	if addressLock > 0 {
+		log.Warn().
+			Str("username", username).
+			Str("address", address).
+			Dur("duration", addressLock).
+			Msg("Locked out address after repeated failed attempts.")
+	}
+
+	if addressLock > userLock {
+		return addressLock
+	}
+
+	return userLock
+}
+
+func (repository lockoutRepositoryImpl) Succeed(username, address string) {
+	if _, err := repository.redis.Del(context.Background(), repository.key("user", username)).Result(); err != nil {
+		log.Error().Err(err).Msg("Cannot reset failed attempts for: " + username)
+	}
+}
+
+func (repository lockoutRepositoryImpl) Clear(username, address string) error {
+	keys := []string{}
+
+	if len(username) > 0 {
+		key := repository.key("user", username)
+		keys = append(keys, key, key+"-locked")
+	}
+
+	if len(address) > 0 {
+		key := repository.key("address", address)
+		keys = append(keys, key, key+"-locked")
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return repository.redis.Del(context.Background(), keys...).Err()
+}
+
+// Count a failure and lock the key when the threshold is reached, doubling the lock on every failure after it.
+func (repository lockoutRepositoryImpl) increment(key string, threshold int64) time.Duration {
+	context := context.Background()
+
+	var attempts *redis.IntCmd
+
+	_, err := repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		attempts = p.Incr(context, key)
+		p.Expire(context, key, repository.config.GetLockoutWindow())
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot count failed attempt for: " + key)
+		return 0
+	}
+
+	duration := lockDuration(attempts.Val(), threshold, repository.config.GetLockoutBase(), repository.config.GetLockoutMax())
+
+	if duration == 0 {
+		return 0
+	}
+
+	if err := repository.redis.Set(context, key+"-locked", attempts.Val(), duration).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot lock out: " + key)
+		return 0
+	}
+
+	return duration
+}
+
+// Lock for the amount of attempts, none below the threshold and doubling from base on every attempt over it up to max.
+func lockDuration(attempts, threshold int64, base, max time.Duration) time.Duration {
+	exceeded := attempts - threshold
+
+	if threshold <= 0 || exceeded < 0 {
+		return 0
+	}
+
+	duration := base
+
+	if duration < minimumLockout {
+		duration = minimumLockout
+	}
+
+	for i := int64(0); i < exceeded && duration < max; i++ {
+		duration *= 2
+	}
+
+	if duration > max && max >= minimumLockout {
+		duration = max
+	}
+
+	return duration
+}
+
+// Keys checked for the attempt, the address one only when the address is known.
+func (repository lockoutRepositoryImpl) keys(username, address string) []string {
+	keys := []string{repository.key("user", username)}
+
+	if len(address) > 0 {
+		keys = append(keys, repository.key("address", address))
+	}
+
+	return keys
+}
+
+func (repository lockoutRepositoryImpl) key(kind, value string) string {
+	return repository.config.GetLockoutKey() + "-" + kind + "-" + value
+}
+
+func CreateLockoutRepository(client *redis.Client, config *config.Config) LockoutRepository {
+	return lockoutRepositoryImpl{
+		redis:  client,
+		config: config,
+	}
+}
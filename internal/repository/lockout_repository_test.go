@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int64
+		threshold int64
+		base, max time.Duration
+		duration  time.Duration
+	}{
+		{"below threshold", 4, 5, time.Minute, time.Hour, 0},
+		{"on threshold", 5, 5, time.Minute, time.Hour, time.Minute},
+		{"one over threshold", 6, 5, time.Minute, time.Hour, 2 * time.Minute},
+		{"three over threshold", 8, 5, time.Minute, time.Hour, 8 * time.Minute},
+		{"capped on max", 20, 5, time.Minute, time.Hour, time.Hour},
+		{"many attempts stay capped", 1 << 40, 5, time.Minute, time.Hour, time.Hour},
+		{"disabled threshold", 100, 0, time.Minute, time.Hour, 0},
+		{"zero base is clamped", 5, 5, 0, time.Hour, minimumLockout},
+		{"zero base still doubles", 7, 5, 0, time.Hour, 4 * minimumLockout},
+		{"zero max keeps the minimum", 5, 5, 0, 0, minimumLockout},
+		{"base over max", 5, 5, 2 * time.Hour, time.Hour, time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if duration := lockDuration(test.attempts, test.threshold, test.base, test.max); duration != test.duration {
+				t.Errorf("lockDuration = %s, want %s", duration, test.duration)
+			}
+		})
+	}
+}
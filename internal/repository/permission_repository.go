@@ -0,0 +1,420 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dot separated lowercase segments, optionally negated and ending on a wildcard.
+var nodePattern = regexp.MustCompile(`^-?(\*|[a-z0-9_][a-z0-9_\-]*(\.[a-z0-9_][a-z0-9_\-]*)*(\.\*)?)$`)
+
+var (
+	ErrInvalidNode      = errors.New("permission node must be dot separated lowercase segments, optionally negated or ending on a wildcard")
+	ErrInheritanceCycle = errors.New("group would inherit from itself")
+)
+
+// Effective permissions of an account, nodes map to whether they are granted or negated.
+type Permissions struct {
+	// The heaviest group applying on the context, shown as the rank of the account.
+	Primary data.GroupType `json:"primary"`
+
+	Groups      []data.GroupType `json:"groups"`
+	Permissions map[string]bool  `json:"permissions"`
+}
+
+// Whether the node is granted, the most specific node or wildcard wins. Nodes never set are denied.
+func (permissions Permissions) Allows(node string) bool {
+	node = strings.ToLower(node)
+
+	if value, ok := permissions.Permissions[node]; ok {
+		return value
+	}
+
+	segments := strings.Split(node, ".")
+
+	for i := len(segments) - 1; i > 0; i-- {
+		if value, ok := permissions.Permissions[strings.Join(segments[:i], ".")+".*"]; ok {
+			return value
+		}
+	}
+
+	return permissions.Permissions["*"]
+}
+
+// Cached along with the version of the nodes it was resolved from.
+type cachedPermissions struct {
+	Version     int64
+	Permissions Permissions
+}
+
+type PermissionRepository interface {
+	ListNodes(group data.GroupType) ([]string, error)
+	AddNodes(group data.GroupType, nodes []string) error
+	RemoveNodes(group data.GroupType, nodes []string) error
+
+	ListParents(group data.GroupType) ([]data.GroupType, error)
+	// Inherit from the parent, ErrInheritanceCycle when the parent already inherits from the group.
+	AddParent(group, parent data.GroupType) error
+	RemoveParent(group, parent data.GroupType) error
+
+	// Resolve the permissions of the non-expired groups of the account applying on the context, along with the default group.
+	// Groups of higher weight take precedence, as do the nodes of a group over the inherited ones.
+	Resolve(account data.Account, context data.GroupContext) (Permissions, error)
+}
+
+type permissionRepositoryImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	groups GroupRepository
+	config *config.Config
+}
+
+func (repository permissionRepositoryImpl) ListNodes(group data.GroupType) ([]string, error) {
+	nodes := []string{}
+
+	err := repository.db.Model(&GroupPermissionImpl{}).
+		Where("role = ?", group).
+		Order("node ASC").
+		Pluck("node", &nodes).Error
+
+	return nodes, err
+}
+
+func (repository permissionRepositoryImpl) AddNodes(group data.GroupType, nodes []string) error {
+	rows := make([]GroupPermissionImpl, 0, len(nodes))
+
+	for _, node := range nodes {
+		node = strings.ToLower(node)
+
+		if len(node) > 128 || !nodePattern.MatchString(node) {
+			return ErrInvalidNode
+		}
+
+		rows = append(rows, GroupPermissionImpl{
+			Group: group,
+			Node:  node,
+
+			CreatedAt: time.Now(),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if err := repository.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return err
+	}
+
+	return BumpPermissions(repository.redis, repository.config)
+}
+
+func (repository permissionRepositoryImpl) RemoveNodes(group data.GroupType, nodes []string) error {
+	for i := range nodes {
+		nodes[i] = strings.ToLower(nodes[i])
+	}
+
+	if err := repository.db.Where("role = ? AND node IN ?", group, nodes).Delete(&GroupPermissionImpl{}).Error; err != nil {
+		return err
+	}
+
+	return BumpPermissions(repository.redis, repository.config)
+}
+
+func (repository permissionRepositoryImpl) ListParents(group data.GroupType) ([]data.GroupType, error) {
+	parents := []data.GroupType{}
+
+	err := repository.db.Model(&GroupParentImpl{}).
+		Where("role = ?", group).
+		Order("parent ASC").
+		Pluck("parent", &parents).Error
+
+	return parents, err
+}
+
+func (repository permissionRepositoryImpl) AddParent(group, parent data.GroupType) error {
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		var parents []GroupParentImpl
+
+		// Locked so two concurrent inserts cannot close a cycle together.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&parents).Error; err != nil {
+			return err
+		}
+
+		graph := map[data.GroupType][]data.GroupType{}
+
+		for _, row := range parents {
+			graph[row.Group] = append(graph[row.Group], row.Parent)
+		}
+
+		if inherits(graph, parent, group, map[data.GroupType]bool{}) {
+			return ErrInheritanceCycle
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&GroupParentImpl{
+			Group:  group,
+			Parent: parent,
+
+			CreatedAt: time.Now(),
+		}).Error
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return BumpPermissions(repository.redis, repository.config)
+}
+
+func (repository permissionRepositoryImpl) RemoveParent(group, parent data.GroupType) error {
+	if err := repository.db.Where("role = ? AND parent = ?", group, parent).Delete(&GroupParentImpl{}).Error; err != nil {
+		return err
+	}
+
+	return BumpPermissions(repository.redis, repository.config)
+}
+
+func (repository permissionRepositoryImpl) Resolve(account data.Account, target data.GroupContext) (Permissions, error) {
+	context := context.Background()
+
+	// Every context of the account is cached on the same hash, so it is dropped at once.
+	key := PermissionKey(repository.config, account.GetUniqueId())
+	field := target.String()
+
+	version, err := repository.redis.Get(context, repository.config.GetPermissionKey()+"-version").Int64()
+
+	if err != nil && err != redis.Nil {
+		return Permissions{}, err
+	}
+
+	if cached, err := repository.redis.HGet(context, key, field).Bytes(); err == nil {
+		var entry cachedPermissions
+
+		if json.Unmarshal(cached, &entry) == nil && entry.Version == version {
+			return entry.Permissions, nil
+		}
+	}
+
+	permissions, lifetime, err := repository.resolve(account, target)
+
+	if err != nil {
+		return Permissions{}, err
+	}
+
+	if lifetime > 0 {
+		encoded, err := json.Marshal(cachedPermissions{
+			Version:     version,
+			Permissions: permissions,
+		})
+
+		if err == nil {
+			_, err = repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+				p.HSet(context, key, field, encoded)
+				p.Expire(context, key, lifetime)
+
+				return nil
+			})
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot cache permissions for account: " + account.GetUniqueId())
+		}
+	}
+
+	return permissions, nil
+}
+
+// Resolve the permissions from database, along with how long they hold before a group expires.
+func (repository permissionRepositoryImpl) resolve(account data.Account, context data.GroupContext) (Permissions, time.Duration, error) {
+	registry, err := repository.groups.ListGroups()
+
+	if err != nil {
+		return Permissions{}, 0, err
+	}
+
+	weights := map[data.GroupType]int{}
+	active := map[data.GroupType]bool{}
+
+	for _, group := range registry {
+		weights[group.GetName()] = group.GetWeight()
+
+		if group.IsDefault() {
+			active[group.GetName()] = true
+		}
+	}
+
+	now := time.Now()
+	lifetime := repository.config.GetExpireInterval()
+
+	for _, info := range account.GetGroupSet() {
+		if !info.ExpireAt.After(now) {
+			continue
+		}
+
+		// Bounded by grants of other contexts as well, they share the cached hash.
+		if until := info.ExpireAt.Sub(now); until < lifetime {
+			lifetime = until
+		}
+
+		if info.Context.Matches(context) {
+			active[info.Group] = true
+		}
+	}
+
+	var nodes []GroupPermissionImpl
+
+	if err := repository.db.Find(&nodes).Error; err != nil {
+		return Permissions{}, 0, err
+	}
+
+	var parents []GroupParentImpl
+
+	if err := repository.db.Find(&parents).Error; err != nil {
+		return Permissions{}, 0, err
+	}
+
+	own := map[data.GroupType][]string{}
+
+	for _, row := range nodes {
+		own[row.Group] = append(own[row.Group], row.Node)
+	}
+
+	graph := map[data.GroupType][]data.GroupType{}
+
+	for _, row := range parents {
+		graph[row.Group] = append(graph[row.Group], row.Parent)
+	}
+
+	byWeight := func(groups []data.GroupType) []data.GroupType {
+		sorted := append([]data.GroupType{}, groups...)
+
+		sort.Slice(sorted, func(i, j int) bool {
+			if weights[sorted[i]] != weights[sorted[j]] {
+				return weights[sorted[i]] < weights[sorted[j]]
+			}
+
+			return sorted[i] < sorted[j]
+		})
+
+		return sorted
+	}
+
+	memo := map[data.GroupType]map[string]bool{}
+
+	var effective func(group data.GroupType, visiting map[data.GroupType]bool) map[string]bool
+
+	effective = func(group data.GroupType, visiting map[data.GroupType]bool) map[string]bool {
+		if result, ok := memo[group]; ok {
+			return result
+		}
+
+		result := map[string]bool{}
+
+		if visiting[group] {
+			return result
+		}
+
+		visiting[group] = true
+
+		// Lighter parents first, so heavier ones and then the group itself override them.
+		for _, parent := range byWeight(graph[group]) {
+			for node, value := range effective(parent, visiting) {
+				result[node] = value
+			}
+		}
+
+		for _, node := range own[group] {
+			result[strings.TrimPrefix(node, "-")] = !strings.HasPrefix(node, "-")
+		}
+
+		delete(visiting, group)
+		memo[group] = result
+
+		return result
+	}
+
+	groups := []data.GroupType{}
+
+	for group := range active {
+		groups = append(groups, group)
+	}
+
+	groups = byWeight(groups)
+
+	permissions := Permissions{
+		Groups:      []data.GroupType{},
+		Permissions: map[string]bool{},
+	}
+
+	for _, group := range groups {
+		for node, value := range effective(group, map[data.GroupType]bool{}) {
+			permissions.Permissions[node] = value
+		}
+	}
+
+	// Listed from the highest weight, as groups are everywhere else.
+	for i := len(groups) - 1; i >= 0; i-- {
+		permissions.Groups = append(permissions.Groups, groups[i])
+	}
+
+	if len(permissions.Groups) > 0 {
+		permissions.Primary = permissions.Groups[0]
+	}
+
+	return permissions, lifetime, nil
+}
+
+// Whether the group inherits from the target, directly or through its parents.
+func inherits(graph map[data.GroupType][]data.GroupType, group, target data.GroupType, visited map[data.GroupType]bool) bool {
+	if group == target {
+		return true
+	}
+
+	if visited[group] {
+		return false
+	}
+
+	visited[group] = true
+
+	for _, parent := range graph[group] {
+		if inherits(graph, parent, target, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hash of the resolved permissions of an account by context, dropped whenever its groups change.
+func PermissionKey(config *config.Config, uuid string) string {
+	return config.GetPermissionKey() + "-" + uuid
+}
+
+// Outdate every resolved permission at once, after nodes or inheritance change.
+func BumpPermissions(redis *redis.Client, config *config.Config) error {
+	return redis.Incr(context.Background(), config.GetPermissionKey()+"-version").Err()
+}
+
+func CreatePermissionRepository(db *gorm.DB, redis *redis.Client, groups GroupRepository, config *config.Config) PermissionRepository {
+	return permissionRepositoryImpl{
+		db:     db,
+		redis:  redis,
+		groups: groups,
+		config: config,
+	}
+}
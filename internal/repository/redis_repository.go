@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	. "github.com/luiz-otavio/galax/internal/impl"
 
@@ -14,6 +15,70 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Set or increment a balance only when it stays within the currency limits. Values are compared as
+// decimal strings, Lua numbers are doubles and would lose precision on 64 bits.
+//
+// KEYS: balance hash, account hash. ARGV: field, "set" or "incr", value, floor, ceiling, ttl.
+var boundedBalance = redis.NewScript(`
+local function compare(a, b)
+	local negativeA, negativeB = string.sub(a, 1, 1) == '-', string.sub(b, 1, 1) == '-'
+
+	if negativeA ~= negativeB then
+		return negativeA and -1 or 1
+	end
+
+	if negativeA then
+		a, b = string.sub(b, 2), string.sub(a, 2)
+	end
+
+	if #a ~= #b then
+		return #a < #b and -1 or 1
+	end
+
+	if a == b then
+		return 0
+	end
+
+	return a < b and -1 or 1
+end
+
+-- Only accounts on cache are touched, a partial hash would shadow the database.
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return false
+end
+
+local field, mode, value, floor, ceiling, ttl = ARGV[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5], ARGV[6]
+local previous = redis.call('HGET', KEYS[1], field)
+
+if mode == 'incr' then
+	local result = redis.pcall('HINCRBY', KEYS[1], field, value)
+
+	if type(result) == 'table' and result.err then
+		return redis.error_reply('OUT_OF_BOUNDS ' .. result.err)
+	end
+
+	value = redis.call('HGET', KEYS[1], field)
+end
+
+if compare(value, floor) < 0 or compare(value, ceiling) > 0 then
+	if previous then
+		redis.call('HSET', KEYS[1], field, previous)
+	else
+		redis.call('HDEL', KEYS[1], field)
+	end
+
+	return redis.error_reply('OUT_OF_BOUNDS')
+end
+
+redis.call('HSET', KEYS[1], field, value)
+
+if KEYS[1] ~= KEYS[2] then
+	redis.call('EXPIRE', KEYS[1], ttl)
+end
+
+return value
+`)
+
 type RedisRepository interface {
 	GetRedis() *redis.Client
 	GetConfig() *config.Config
@@ -21,12 +86,22 @@ type RedisRepository interface {
 	LoadAccount(uuid string) data.Account
 	SaveAccount(account data.Account)
 
+	// Unique ids of every account on cache.
+	CachedAccounts() ([]string, error)
+	// Drop every key of the account along with its groups, the next read loads it from MySQL.
+	InvalidateAccount(uuid string)
+	// Drop the resolved permissions only, for grants changed on accounts which may not be cached.
+	InvalidatePermissions(uuid string)
+
 	RemoveGroup(account data.Account, groupInfo data.GroupInfo)
 	AddGroup(account data.Account, groupInfo data.GroupInfo)
 
-	UpdateCash(uuid string, cash int32)
-	AddCash(uuid string, cash int32)
-	TakeCash(uuid string, cash int32)
+	UpdateCash(uuid string, cash int64)
+	AddCash(uuid string, cash int64)
+	TakeCash(uuid string, cash int64)
+	// Cache the balance on the currency, the default one is kept on the account cash.
+	UpdateWallet(uuid string, currency string, balance int64)
+	TransferCash(currency string, from string, fromCash int64, to string, toCash int64)
 
 	UpdateMetadata(uuid string, key string, value string)
 }
@@ -52,7 +127,7 @@ func (cache repositoryImpl) LoadAccount(uuid string) data.Account {
 		return nil
 	}
 
-	cash, err := strconv.Atoi(result["cash"])
+	cash, err := strconv.ParseInt(result["cash"], 10, 64)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Cannot parse cash entry for account: " + uuid)
@@ -73,6 +148,30 @@ func (cache repositoryImpl) LoadAccount(uuid string) data.Account {
 		return nil
 	}
 
+	balances, err := client.HGetAll(context, cache.config.GetAccountKey()+"-"+uuid+"-wallets").Result()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot load wallets for account: " + uuid)
+		return nil
+	}
+
+	wallets := []data.Wallet{}
+
+	for currency, value := range balances {
+		balance, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot parse wallet " + currency + " for account: " + uuid)
+			return nil
+		}
+
+		wallets = append(wallets, data.Wallet{
+			User:     uuid,
+			Currency: currency,
+			Balance:  balance,
+		})
+	}
+
 	groups, err := client.SMembers(context, cache.config.GetAccountKey()+"-"+uuid+"-groups").Result()
 
 	if err != nil {
@@ -114,7 +213,7 @@ func (cache repositoryImpl) LoadAccount(uuid string) data.Account {
 		return nil
 	}
 
-	accountType, err := util.ParseAccountType(result["type"])
+	accountType, err := util.ParseAccountType(result["accountType"])
 
 	if err != nil {
 		log.Error().Err(err).Msg("Cannot parse account type from account: " + uuid)
@@ -127,12 +226,14 @@ func (cache repositoryImpl) LoadAccount(uuid string) data.Account {
 		},
 
 		AccountType: accountType,
+		PremiumId:   result["premiumId"],
 
 		Name: result["name"],
-		Cash: int32(cash),
+		Cash: cash,
 
 		GroupSet:    groupSet,
 		MetadataSet: metadataSet,
+		Wallets:     wallets,
 
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
@@ -149,7 +250,8 @@ func (cache repositoryImpl) SaveAccount(account data.Account) {
 		_, err = p.HMSet(context, key+"-"+account.GetUniqueId(), map[string]interface{}{
 			"name":        account.GetName(),
 			"cash":        account.GetCash(),
-			"accountType": account.GetAccountType(),
+			"accountType": string(account.GetAccountType()),
+			"premiumId":   account.GetPremiumId(),
 			"createdAt":   account.GetCreatedAt().Unix(),
 			"updatedAt":   account.GetUpdatedAt().Unix(),
 		}).Result()
@@ -168,7 +270,7 @@ func (cache repositoryImpl) SaveAccount(account data.Account) {
 			"vanish":          metadataSet.Vanish,
 			"see_all_players": metadataSet.SeeAllPlayers,
 			"flying":          metadataSet.Flying,
-			"current_group":   metadataSet.CurrentGroup,
+			"current_group":   string(metadataSet.CurrentGroup),
 			"staff_chat":      metadataSet.SeeAllStaffChat,
 			"see_all_reports": metadataSet.SeeAllReports,
 		}).Result()
@@ -178,14 +280,53 @@ func (cache repositoryImpl) SaveAccount(account data.Account) {
 			return err
 		}
 
+		if _, err = cache.rank(context, p, account.GetUniqueId(), cache.config.GetDefaultCurrency(), account.GetCash()).Result(); err != nil {
+			log.Error().Err(err).Msg("Cannot execute rank step for saving account for: " + account.GetUniqueId())
+			return err
+		}
+
+		for _, wallet := range account.GetWallets() {
+			if _, err = cache.rank(context, p, account.GetUniqueId(), wallet.Currency, wallet.Balance).Result(); err != nil {
+				log.Error().Err(err).Msg("Cannot execute rank step for saving account for: " + account.GetUniqueId())
+				return err
+			}
+		}
+
+		walletKey := key + "-" + account.GetUniqueId() + "-wallets"
+
+		if len(account.GetWallets()) > 0 {
+			balances := map[string]interface{}{}
+
+			for _, wallet := range account.GetWallets() {
+				balances[wallet.Currency] = wallet.Balance
+			}
+
+			if _, err = p.HMSet(context, walletKey, balances).Result(); err != nil {
+				log.Error().Err(err).Msg("Cannot execute wallet step for saving account for: " + account.GetUniqueId())
+				return err
+			}
+
+			if _, err = p.Expire(context, walletKey, cache.config.GetExpireInterval()).Result(); err != nil {
+				log.Error().Err(err).Msg("Cannot execute expire key step for account wallets: " + account.GetUniqueId())
+				return err
+			}
+		}
+
+		// Permissions are resolved from the groups saved below.
+		if _, err = p.Del(context, PermissionKey(cache.config, account.GetUniqueId())).Result(); err != nil {
+			log.Error().Err(err).Msg("Cannot execute permission step for saving account for: " + account.GetUniqueId())
+			return err
+		}
+
 		groupKey := key + "-" + account.GetUniqueId() + "-groups"
 		for _, group := range account.GetGroupSet() {
-			if _, err = p.SAdd(context, groupKey, group.Group).Result(); err != nil {
+			if _, err = p.SAdd(context, groupKey, group.Key()).Result(); err != nil {
 				log.Error().Err(err).Msg("Cannot save group info for account: " + account.GetUniqueId())
 			}
 
-			_, err = p.HMSet(context, groupKey+"-"+string(group.Group), map[string]interface{}{
+			_, err = p.HMSet(context, groupKey+"-"+group.Key(), map[string]interface{}{
 				"author":    group.Author,
+				"context":   group.Context.String(),
 				"createdAt": group.CreatedAt.Unix(),
 				"expireAt":  group.ExpireAt.Unix(),
 			}).Result()
@@ -195,7 +336,7 @@ func (cache repositoryImpl) SaveAccount(account data.Account) {
 				continue
 			}
 
-			if _, err = p.Expire(context, groupKey+"-"+string(group.Group), cache.config.GetExpireInterval()).Result(); err != nil {
+			if _, err = p.Expire(context, groupKey+"-"+group.Key(), cache.config.GetExpireInterval()).Result(); err != nil {
 				log.Error().Err(err).Msg("Cannot execute expire key step for saving group info: " + account.GetUniqueId())
 			}
 		}
@@ -223,27 +364,168 @@ func (cache repositoryImpl) SaveAccount(account data.Account) {
 	}
 }
 
-func (cache repositoryImpl) UpdateCash(uuid string, cash int32) {
-	_, err := cache.redis.HSet(context.Background(), cache.config.GetAccountKey()+"-"+uuid, "cash", cash).Result()
+func (cache repositoryImpl) CachedAccounts() ([]string, error) {
+	context := context.Background()
 
-	if err != nil {
-		log.Error().Err(err).Msg("Cannot update cash for account: " + uuid)
+	prefix := cache.config.GetAccountKey() + "-"
+	iterator := cache.redis.Scan(context, 0, prefix+"*", 1000).Iterator()
+
+	accounts := []string{}
+
+	for iterator.Next(context) {
+		// Only the account hash itself, the metadata, wallet and group keys share the prefix.
+		if unique := strings.TrimPrefix(iterator.Val(), prefix); util.EnsureUUID(unique) {
+			accounts = append(accounts, unique)
+		}
 	}
+
+	return accounts, iterator.Err()
 }
 
-func (cache repositoryImpl) AddCash(uuid string, cash int32) {
-	_, err := cache.redis.HIncrBy(context.Background(), cache.config.GetAccountKey()+"-"+uuid, "cash", int64(cash)).Result()
+func (cache repositoryImpl) InvalidateAccount(uuid string) {
+	context := context.Background()
+
+	key := cache.config.GetAccountKey() + "-" + uuid
+	keys := []string{key, key + "-metadatas", key + "-wallets", key + "-groups", PermissionKey(cache.config, uuid)}
+
+	groups, err := cache.redis.SMembers(context, key+"-groups").Result()
 
 	if err != nil {
-		log.Error().Err(err).Msg("Cannot add cash for account: " + uuid)
+		log.Error().Err(err).Msg("Cannot load groups to invalidate account: " + uuid)
+	}
+
+	for _, group := range groups {
+		keys = append(keys, key+"-groups-"+group)
+	}
+
+	if err := cache.redis.Del(context, keys...).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot invalidate account: " + uuid)
+	}
+}
+
+func (cache repositoryImpl) InvalidatePermissions(uuid string) {
+	if err := cache.redis.Del(context.Background(), PermissionKey(cache.config, uuid)).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot invalidate permissions for account: " + uuid)
 	}
 }
 
-func (cache repositoryImpl) TakeCash(uuid string, cash int32) {
-	_, err := cache.redis.HIncrBy(context.Background(), cache.config.GetAccountKey()+"-"+uuid, "cash", int64(-cash)).Result()
+func (cache repositoryImpl) UpdateCash(uuid string, cash int64) {
+	cache.UpdateWallet(uuid, cache.config.GetDefaultCurrency(), cash)
+}
+
+func (cache repositoryImpl) AddCash(uuid string, cash int64) {
+	cache.increment(uuid, cash, "Cannot add cash for account: "+uuid)
+}
+
+func (cache repositoryImpl) TakeCash(uuid string, cash int64) {
+	amount, err := SubBalance(0, cash)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Cannot take cash for account: " + uuid)
+		cache.invalidate(context.Background(), uuid)
+		return
+	}
+
+	cache.increment(uuid, amount, "Cannot take cash for account: "+uuid)
+}
+
+// Increment the cached cash, ranking the result when the account is on cache.
+func (cache repositoryImpl) increment(uuid string, amount int64, message string) {
+	context := context.Background()
+	currency := cache.config.GetDefaultCurrency()
+
+	balance, err := cache.balance(context, cache.redis, uuid, currency, "incr", amount).Int64()
+
+	if err == redis.Nil {
+		return
+	}
+
+	if err == nil {
+		err = cache.rank(context, cache.redis, uuid, currency, balance).Err()
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg(message)
+		cache.invalidate(context, uuid)
+	}
+}
+
+func (cache repositoryImpl) UpdateWallet(uuid string, currency string, balance int64) {
+	context := context.Background()
+
+	if err := cache.balance(context, cache.redis, uuid, currency, "set", balance).Err(); err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg("Cannot update " + currency + " for account: " + uuid)
+		cache.invalidate(context, uuid)
+	}
+
+	if err := cache.rank(context, cache.redis, uuid, currency, balance).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot rank " + currency + " for account: " + uuid)
+	}
+}
+
+// Write both balances of a transfer at once, so no reader sees the cash in neither or both accounts.
+func (cache repositoryImpl) TransferCash(currency string, from string, fromCash int64, to string, toCash int64) {
+	context := context.Background()
+
+	_, err := cache.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		cache.balance(context, p, from, currency, "set", fromCash)
+		cache.balance(context, p, to, currency, "set", toCash)
+
+		cache.rank(context, p, from, currency, fromCash)
+		cache.rank(context, p, to, currency, toCash)
+
+		return nil
+	})
+
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg("Cannot transfer " + currency + " from " + from + " to " + to)
+		cache.invalidate(context, from, to)
+	}
+}
+
+// Set or increment a cached balance through boundedBalance, the default currency lives on the account cash.
+func (cache repositoryImpl) balance(context context.Context, scripter redis.Scripter, uuid, currency, mode string, value int64) *redis.Cmd {
+	key := cache.config.GetAccountKey() + "-" + uuid
+	target, field := key, "cash"
+
+	if currency != cache.config.GetDefaultCurrency() {
+		target, field = key+"-wallets", currency
+	}
+
+	floor, ceiling := cache.config.GetCurrencyLimits(currency)
+
+	return boundedBalance.Eval(
+		context,
+		scripter,
+		[]string{target, key},
+		field,
+		mode,
+		value,
+		floor,
+		ceiling,
+		int64(cache.config.GetExpireInterval().Seconds()),
+	)
+}
+
+// Keep the balance on the leaderboard of the currency, accounts are ranked whether they are cached or not.
+func (cache repositoryImpl) rank(context context.Context, client redis.Cmdable, uuid, currency string, balance int64) *redis.IntCmd {
+	return client.ZAdd(context, LeaderboardKey(cache.config, currency), &redis.Z{
+		Score:  float64(balance),
+		Member: uuid,
+	})
+}
+
+// Drop the cached accounts, the next read loads them from MySQL again.
+func (cache repositoryImpl) invalidate(context context.Context, uuids ...string) {
+	keys := []string{}
+
+	for _, uuid := range uuids {
+		key := cache.config.GetAccountKey() + "-" + uuid
+		keys = append(keys, key, key+"-wallets")
+	}
+
+	if err := cache.redis.Del(context, keys...).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot invalidate cached accounts.")
 	}
 }
 
@@ -262,12 +544,22 @@ func (cache repositoryImpl) AddGroup(account data.Account, group data.GroupInfo)
 	_, err := cache.redis.TxPipelined(context, func(p redis.Pipeliner) error {
 		var err error
 
-		if _, err = p.SAdd(context, key+"-"+account.GetUniqueId()+"-groups", group.Group).Result(); err != nil {
+		if _, err = p.SAdd(context, key+"-"+account.GetUniqueId()+"-groups", group.Key()).Result(); err != nil {
 			log.Error().Err(err).Msg("Cannot add group info for account: " + account.GetUniqueId())
 		}
 
-		_, err = p.HMSet(context, key+"-"+account.GetUniqueId()+"-groups-"+string(group.Group), map[string]interface{}{
+		// The set is created here when the account had no groups cached, it must expire along with them.
+		if _, err = p.Expire(context, key+"-"+account.GetUniqueId()+"-groups", cache.config.GetExpireInterval()).Result(); err != nil {
+			log.Error().Err(err).Msg("Cannot execute expire key step for account groups: " + account.GetUniqueId())
+		}
+
+		if _, err = p.Del(context, PermissionKey(cache.config, account.GetUniqueId())).Result(); err != nil {
+			log.Error().Err(err).Msg("Cannot invalidate permissions for account: " + account.GetUniqueId())
+		}
+
+		_, err = p.HMSet(context, key+"-"+account.GetUniqueId()+"-groups-"+group.Key(), map[string]interface{}{
 			"author":    group.Author,
+			"context":   group.Context.String(),
 			"createdAt": group.CreatedAt.Unix(),
 			"expireAt":  group.ExpireAt.Unix(),
 		}).Result()
@@ -277,7 +569,7 @@ func (cache repositoryImpl) AddGroup(account data.Account, group data.GroupInfo)
 			return err
 		}
 
-		if _, err = p.Expire(context, key+"-"+account.GetUniqueId()+"-groups-"+string(group.Group), cache.config.GetExpireInterval()).Result(); err != nil {
+		if _, err = p.Expire(context, key+"-"+account.GetUniqueId()+"-groups-"+group.Key(), cache.config.GetExpireInterval()).Result(); err != nil {
 			log.Error().Err(err).Msg("Cannot execute expire key step for adding group info: " + account.GetUniqueId())
 			return err
 		}
@@ -297,11 +589,15 @@ func (cache repositoryImpl) RemoveGroup(account data.Account, group data.GroupIn
 	_, err := cache.redis.TxPipelined(context, func(p redis.Pipeliner) error {
 		var err error
 
-		if _, err = p.SRem(context, key+"-"+account.GetUniqueId()+"-groups", group).Result(); err != nil {
+		if _, err = p.SRem(context, key+"-"+account.GetUniqueId()+"-groups", group.Key()).Result(); err != nil {
 			log.Error().Err(err).Msg("Cannot remove group info for account: " + account.GetUniqueId())
 		}
 
-		_, err = p.Del(context, key+"-"+account.GetUniqueId()+"-groups-"+string(group.Group)).Result()
+		if _, err = p.Del(context, PermissionKey(cache.config, account.GetUniqueId())).Result(); err != nil {
+			log.Error().Err(err).Msg("Cannot invalidate permissions for account: " + account.GetUniqueId())
+		}
+
+		_, err = p.Del(context, key+"-"+account.GetUniqueId()+"-groups-"+group.Key()).Result()
 
 		if err != nil {
 			log.Error().Err(err).Msg("Cannot execute remove step for removing group info: " + account.GetUniqueId())
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+type ResetRepository interface {
+	// Mint a new reset token for the user, replacing any previous one.
+	CreateToken(user string) (string, error)
+
+	// Look up the user owning the token without redeeming it.
+	LoadToken(token string) string
+
+	// Redeem the token once, returning the user it belongs to or empty when invalid.
+	ConsumeToken(token string) string
+}
+
+type resetRepositoryImpl struct {
+	redis  *redis.Client
+	config *config.Config
+}
+
+func (repository resetRepositoryImpl) CreateToken(user string) (string, error) {
+	raw := make([]byte, 32)
+
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(raw)
+	hash := hashToken(token)
+
+	context := context.Background()
+	lifetime := repository.config.GetResetLifetime()
+
+	previous, err := repository.redis.Get(context, repository.key("user-"+user)).Result()
+
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	// Only the hash is stored, a Redis dump must not be enough to reset passwords.
+	_, err = repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		if len(previous) > 0 {
+			p.Del(context, repository.key(previous))
+		}
+
+		p.Set(context, repository.key(hash), user, lifetime)
+		p.Set(context, repository.key("user-"+user), hash, lifetime)
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (repository resetRepositoryImpl) LoadToken(token string) string {
+	if len(token) == 0 {
+		return ""
+	}
+
+	user, err := repository.redis.Get(context.Background(), repository.key(hashToken(token))).Result()
+
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg("Cannot load reset token")
+	}
+
+	return user
+}
+
+func (repository resetRepositoryImpl) ConsumeToken(token string) string {
+	if len(token) == 0 {
+		return ""
+	}
+
+	context := context.Background()
+
+	user, err := repository.redis.GetDel(context, repository.key(hashToken(token))).Result()
+
+	if err != nil {
+		if err != redis.Nil {
+			log.Error().Err(err).Msg("Cannot consume reset token")
+		}
+
+		return ""
+	}
+
+	repository.redis.Del(context, repository.key("user-"+user))
+
+	return user
+}
+
+func (repository resetRepositoryImpl) key(suffix string) string {
+	return repository.config.GetResetKey() + "-" + suffix
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func CreateResetRepository(client *redis.Client, config *config.Config) ResetRepository {
+	return resetRepositoryImpl{
+		redis:  client,
+		config: config,
+	}
+}
@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/internal/worker"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Time given to answer the second factor after the password was accepted.
+const challengeLifetime = 5 * time.Minute
+
+type SessionRepository interface {
+	CreateSession(user, address, agent string) (data.Session, error)
+	LoadSession(id string) data.Session
+
+	TouchSession(session data.Session, extend bool)
+	ListSessions(user string) []data.Session
+
+	RevokeSession(user, id string) error
+	RevokeAll(user string) error
+
+	CreateChallenge(user string) (string, error)
+	LoadChallenge(id string) string
+	DeleteChallenge(id string)
+}
+
+type sessionRepositoryImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	worker worker.Worker
+	config *config.Config
+}
+
+func (repository sessionRepositoryImpl) CreateSession(user, address, agent string) (data.Session, error) {
+	now := time.Now()
+
+	session := CreateSession(
+		uuid.NewString(),
+		user,
+		address,
+		agent,
+		now,
+		now.Add(repository.config.GetRefreshLifetime()),
+	).(SessionImpl)
+
+	// MySQL is the durable copy, it must know the session before handing it out.
+	if err := repository.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	repository.cache(session)
+
+	return session, nil
+}
+
+func (repository sessionRepositoryImpl) LoadSession(id string) data.Session {
+	context := context.Background()
+
+	result, err := repository.redis.HGetAll(context, repository.key(id)).Result()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot load session: " + id)
+		return nil
+	}
+
+	if len(result) > 0 {
+		createdAt, _ := util.ParseUnix(result["createdAt"], 0)
+		lastSeenAt, _ := util.ParseUnix(result["lastSeenAt"], 0)
+		expireAt, _ := util.ParseUnix(result["expireAt"], 0)
+
+		return SessionImpl{
+			UUIDData: data.UUIDData{
+				UUID: id,
+			},
+
+			User: result["user"],
+
+			Address: result["address"],
+			Agent:   result["agent"],
+
+			CreatedAt:  createdAt,
+			LastSeenAt: lastSeenAt,
+			ExpireAt:   expireAt,
+		}
+	}
+
+	// Cache may have been flushed, fallback to the durable copy.
+	var session SessionImpl
+
+	if err := repository.db.Where("unique_id = ? AND revoked = ? AND expire_at > ?", id, false, time.Now()).First(&session).Error; err != nil {
+		return nil
+	}
+
+	repository.cache(session)
+
+	return session
+}
+
+func (repository sessionRepositoryImpl) TouchSession(session data.Session, extend bool) {
+	context := context.Background()
+
+	now := time.Now()
+	values := map[string]interface{}{
+		"lastSeenAt": now.Unix(),
+	}
+
+	updates := map[string]interface{}{
+		"last_seen_at": now,
+	}
+
+	expireAt := session.GetExpireAt()
+
+	if extend {
+		expireAt = now.Add(repository.config.GetRefreshLifetime())
+
+		values["expireAt"] = expireAt.Unix()
+		updates["expire_at"] = expireAt
+	}
+
+	_, err := repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		p.HSet(context, repository.key(session.GetUniqueId()), values)
+		p.ExpireAt(context, repository.key(session.GetUniqueId()), expireAt)
+
+		// The index must outlive every session on it, or revoking all of them would miss this one.
+		if extend {
+			p.SAdd(context, repository.userKey(session.GetUser()), session.GetUniqueId())
+			p.ExpireAt(context, repository.userKey(session.GetUser()), expireAt)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot touch session: " + session.GetUniqueId())
+	}
+
+	id := session.GetUniqueId()
+
+	repository.worker.Do(func(d *gorm.DB) {
+		d.Model(SessionImpl{}).
+			Where("unique_id = ?", id).
+			Updates(updates)
+	})
+}
+
+func (repository sessionRepositoryImpl) ListSessions(user string) []data.Session {
+	var sessions []SessionImpl
+
+	if err := repository.db.Where("user = ? AND revoked = ? AND expire_at > ?", user, false, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error; err != nil {
+		log.Error().Err(err).Msg("Cannot list sessions for: " + user)
+		return []data.Session{}
+	}
+
+	result := make([]data.Session, 0, len(sessions))
+
+	for _, session := range sessions {
+		// Last seen is kept up to date on cache, prefer it over the database.
+		if cached := repository.LoadSession(session.GetUniqueId()); cached != nil {
+			result = append(result, cached)
+		} else {
+			result = append(result, session)
+		}
+	}
+
+	return result
+}
+
+func (repository sessionRepositoryImpl) RevokeSession(user, id string) error {
+	if err := repository.db.Model(SessionImpl{}).
+		Where("unique_id = ? AND user = ?", id, user).
+		Update("revoked", true).Error; err != nil {
+		return err
+	}
+
+	context := context.Background()
+
+	_, err := repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		p.Del(context, repository.key(id))
+		p.SRem(context, repository.userKey(user), id)
+
+		return nil
+	})
+
+	return err
+}
+
+func (repository sessionRepositoryImpl) RevokeAll(user string) error {
+	if err := repository.db.Model(SessionImpl{}).
+		Where("user = ? AND revoked = ?", user, false).
+		Update("revoked", true).Error; err != nil {
+		return err
+	}
+
+	var ids []string
+
+	// MySQL knows every session still cached, the index may have expired before some of them.
+	if err := repository.db.Model(SessionImpl{}).
+		Where("user = ? AND expire_at > ?", user, time.Now()).
+		Pluck("unique_id", &ids).Error; err != nil {
+		return err
+	}
+
+	context := context.Background()
+
+	members, err := repository.redis.SMembers(context, repository.userKey(user)).Result()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		for _, id := range append(ids, members...) {
+			p.Del(context, repository.key(id))
+		}
+
+		p.Del(context, repository.userKey(user))
+
+		return nil
+	})
+
+	return err
+}
+
+func (repository sessionRepositoryImpl) CreateChallenge(user string) (string, error) {
+	id := uuid.NewString()
+
+	if err := repository.redis.Set(context.Background(), repository.key("challenge-"+id), user, challengeLifetime).Err(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (repository sessionRepositoryImpl) LoadChallenge(id string) string {
+	if !util.EnsureUUID(id) {
+		return ""
+	}
+
+	user, err := repository.redis.Get(context.Background(), repository.key("challenge-"+id)).Result()
+
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg("Cannot load challenge: " + id)
+	}
+
+	return user
+}
+
+func (repository sessionRepositoryImpl) DeleteChallenge(id string) {
+	if err := repository.redis.Del(context.Background(), repository.key("challenge-"+id)).Err(); err != nil {
+		log.Error().Err(err).Msg("Cannot delete challenge: " + id)
+	}
+}
+
+func (repository sessionRepositoryImpl) cache(session data.Session) {
+	context := context.Background()
+
+	_, err := repository.redis.TxPipelined(context, func(p redis.Pipeliner) error {
+		p.HSet(context, repository.key(session.GetUniqueId()), map[string]interface{}{
+			"user":       session.GetUser(),
+			"address":    session.GetAddress(),
+			"agent":      session.GetAgent(),
+			"createdAt":  session.GetCreatedAt().Unix(),
+			"lastSeenAt": session.GetLastSeenAt().Unix(),
+			"expireAt":   session.GetExpireAt().Unix(),
+		})
+
+		p.ExpireAt(context, repository.key(session.GetUniqueId()), session.GetExpireAt())
+
+		p.SAdd(context, repository.userKey(session.GetUser()), session.GetUniqueId())
+		p.Expire(context, repository.userKey(session.GetUser()), repository.config.GetRefreshLifetime())
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot cache session: " + session.GetUniqueId())
+	}
+}
+
+func (repository sessionRepositoryImpl) key(id string) string {
+	return repository.config.GetSessionKey() + "-" + id
+}
+
+func (repository sessionRepositoryImpl) userKey(user string) string {
+	return repository.config.GetSessionKey() + "-user-" + user
+}
+
+func CreateSessionRepository(db *gorm.DB, client *redis.Client, worker worker.Worker, config *config.Config) SessionRepository {
+	return sessionRepositoryImpl{
+		db:     db,
+		redis:  client,
+		worker: worker,
+		config: config,
+	}
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons of the ledger entries written by store events.
+const (
+	StoreReason      = "store"
+	ChargebackReason = "chargeback"
+	RefundReason     = "refund"
+)
+
+var (
+	ErrPurchaseProcessed = errors.New("purchase already processed")
+	ErrPurchaseNotFound  = errors.New("purchase not found")
+)
+
+// What a store package grants, cash and group are both optional.
+type StoreReward struct {
+	Currency string
+	Cash     int64
+
+	Group    data.GroupType
+	Duration time.Duration
+}
+
+// Changes made by a store event, so callers can mirror them on cache.
+type StoreOutcome struct {
+	Purchase    data.Purchase
+	Transaction data.Transaction
+
+	Group *data.GroupInfo
+
+	// Whether the group was taken away entirely instead of shortened.
+	Removed bool
+}
+
+type StoreRepository interface {
+	// Grant the reward once per store transaction, ErrPurchaseProcessed is returned for repeated ones.
+	Fulfil(transaction, user, pkg string, reward StoreReward, entry LedgerEntry) (StoreOutcome, error)
+
+	// Take back what the transaction granted, the cash down to the currency floor and the group duration.
+	// The status is either "chargeback" or "refund", ErrPurchaseProcessed is returned when already revoked.
+	Revoke(transaction, status string, entry LedgerEntry) (StoreOutcome, error)
+}
+
+type storeRepositoryImpl struct {
+	db     *gorm.DB
+	ledger LedgerRepository
+	config *config.Config
+}
+
+func (repository storeRepositoryImpl) Fulfil(transaction, user, pkg string, reward StoreReward, entry LedgerEntry) (StoreOutcome, error) {
+	var outcome StoreOutcome
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("unique_id").Where("unique_id = ?", user).First(&AccountImpl{}).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("transaction = ?", transaction).First(&PurchaseImpl{}).Error
+
+		if err == nil {
+			return ErrPurchaseProcessed
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		purchase := CreatePurchase(uuid.NewString(), transaction, user, pkg, reward.Currency, reward.Cash, reward.Group, reward.Duration).(PurchaseImpl)
+
+		// Created first, so a concurrent delivery of the same transaction fails on the unique index.
+		if err := tx.Create(&purchase).Error; err != nil {
+			return err
+		}
+
+		if reward.Cash != 0 {
+			entry.Reason = StoreReason
+			entry.Actor = transaction
+
+			outcome.Transaction, err = repository.ledger.ApplyWith(tx, user, reward.Currency, func(balance int64) (int64, error) {
+				return AddBalance(balance, reward.Cash)
+			}, entry)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(reward.Group) > 0 {
+			granted, err := GrantGroup(tx, user, purchase.UUID, reward.Group, reward.Duration)
+
+			if err != nil {
+				return err
+			}
+
+			outcome.Group = &granted
+		}
+
+		outcome.Purchase = purchase
+
+		return nil
+	})
+
+	return outcome, err
+}
+
+func (repository storeRepositoryImpl) Revoke(transaction, status string, entry LedgerEntry) (StoreOutcome, error) {
+	var outcome StoreOutcome
+
+	err := repository.db.Transaction(func(tx *gorm.DB) error {
+		var purchase PurchaseImpl
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("transaction = ?", transaction).
+			First(&purchase).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPurchaseNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if purchase.Status != "fulfilled" {
+			return ErrPurchaseProcessed
+		}
+
+		if purchase.Cash != 0 {
+			floor, _ := repository.config.GetCurrencyLimits(purchase.Currency)
+
+			entry.Reason = status
+			entry.Actor = transaction
+
+			// The cash may have been spent already, take back whatever is left above the floor.
+			outcome.Transaction, err = repository.ledger.ApplyWith(tx, purchase.User, purchase.Currency, func(balance int64) (int64, error) {
+				next, err := SubBalance(balance, purchase.Cash)
+
+				if err != nil || next < floor {
+					return floor, nil
+				}
+
+				return next, nil
+			}, entry)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(purchase.Group) > 0 {
+			group, removed, err := shortenGroup(tx, purchase.User, purchase.Group, purchase.GetDuration())
+
+			if err != nil {
+				return err
+			}
+
+			outcome.Group = group
+			outcome.Removed = removed
+		}
+
+		purchase.Status = status
+		purchase.UpdatedAt = time.Now()
+
+		if err := tx.Model(&purchase).Updates(map[string]interface{}{
+			"status":     purchase.Status,
+			"updated_at": purchase.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		outcome.Purchase = purchase
+
+		return nil
+	})
+
+	return outcome, err
+}
+
+// Take the duration off the grant without context of the user, removing it when nothing is left.
+// Purchases only grant groups without context, so grants limited to one are never touched.
+func shortenGroup(tx *gorm.DB, user string, role data.GroupType, duration time.Duration) (*data.GroupInfo, bool, error) {
+	var group data.GroupInfo
+
+	err := tx.Where("user = ? AND role = ? AND context = ''", user, role).First(&group).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	group.ExpireAt = group.ExpireAt.Add(-duration)
+
+	if !group.ExpireAt.After(time.Now()) {
+		err = tx.Where("user = ? AND role = ? AND context = ''", user, role).Delete(&data.GroupInfo{}).Error
+
+		return &group, true, err
+	}
+
+	err = tx.Model(&data.GroupInfo{}).
+		Where("user = ? AND role = ? AND context = ''", user, role).
+		Update("expire_at", group.ExpireAt).Error
+
+	return &group, false, err
+}
+
+func CreateStoreRepository(db *gorm.DB, ledger LedgerRepository, config *config.Config) StoreRepository {
+	return storeRepositoryImpl{
+		db:     db,
+		ledger: ledger,
+		config: config,
+	}
+}
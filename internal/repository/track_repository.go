@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var trackPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// Expiry of grants entering a track without one, the latest date MySQL keeps.
+var Permanent = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+var (
+	ErrInvalidTrack  = errors.New("track name must be 1 to 32 lowercase letters, digits or underscores")
+	ErrInvalidLadder = errors.New("track must list distinct groups from the registry")
+	ErrTrackNotFound = errors.New("unknown track")
+	ErrTrackExists   = errors.New("track already exists")
+	ErrTrackEnd      = errors.New("account is already on the last group of the track")
+	ErrNotOnTrack    = errors.New("account holds no group of the track")
+	ErrOutranked     = errors.New("author cannot act on groups above their own")
+)
+
+// Grants swapped by a promotion or demotion, nil when entering or leaving the track.
+type Promotion struct {
+	Track string `json:"track"`
+
+	From *data.GroupInfo `json:"from"`
+	To   *data.GroupInfo `json:"to"`
+
+	// Current group of the account after the swap, empty when it was kept.
+	CurrentGroup data.GroupType `json:"current_group,omitempty"`
+}
+
+type TrackRepository interface {
+	ListTracks() ([]data.Track, error)
+
+	// Find the track by name in any case, ErrTrackNotFound when it does not exist.
+	FindTrack(name string) (data.Track, error)
+
+	CreateTrack(track data.Track) (data.Track, error)
+	UpdateTrack(track data.Track) (data.Track, error)
+	DeleteTrack(name string) error
+
+	// Move the user one group up the track, entering it on the first group until the expiry, permanently when nil.
+	// ErrOutranked when the group outweighs the highest group the author holds everywhere.
+	Promote(user, author, track string, expireAt *time.Time) (Promotion, error)
+
+	// Move the user one group down the track, leaving it from the first group.
+	Demote(user, author, track string) (Promotion, error)
+}
+
+type trackRepositoryImpl struct {
+	db     *gorm.DB
+	groups GroupRepository
+}
+
+func (repository trackRepositoryImpl) ListTracks() ([]data.Track, error) {
+	var tracks []TrackImpl
+
+	if err := repository.db.Order("name ASC").Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]data.Track, 0, len(tracks))
+
+	for _, track := range tracks {
+		result = append(result, track)
+	}
+
+	return result, nil
+}
+
+func (repository trackRepositoryImpl) FindTrack(name string) (data.Track, error) {
+	var track TrackImpl
+
+	if err := repository.db.Where("name = ?", strings.ToLower(name)).First(&track).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrackNotFound
+		}
+
+		return nil, err
+	}
+
+	return track, nil
+}
+
+func (repository trackRepositoryImpl) CreateTrack(track data.Track) (data.Track, error) {
+	target := track.(TrackImpl)
+	target.Name = strings.ToLower(target.Name)
+
+	if !trackPattern.MatchString(target.Name) {
+		return nil, ErrInvalidTrack
+	}
+
+	ladder, err := repository.ladderOf(target.Groups)
+
+	if err != nil {
+		return nil, err
+	}
+
+	target.Groups = ladder
+
+	err = repository.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Where("name = ?", target.Name).First(&TrackImpl{}).Error == nil {
+			return ErrTrackExists
+		}
+
+		return tx.Create(&target).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (repository trackRepositoryImpl) UpdateTrack(track data.Track) (data.Track, error) {
+	target := track.(TrackImpl)
+	target.UpdatedAt = time.Now()
+
+	ladder, err := repository.ladderOf(target.Groups)
+
+	if err != nil {
+		return nil, err
+	}
+
+	target.Groups = ladder
+
+	result := repository.db.Model(&TrackImpl{}).Where("name = ?", target.Name).Updates(map[string]interface{}{
+		"ladder":     target.Groups,
+		"updated_at": target.UpdatedAt,
+	})
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, ErrTrackNotFound
+	}
+
+	return target, nil
+}
+
+func (repository trackRepositoryImpl) DeleteTrack(name string) error {
+	result := repository.db.Where("name = ?", strings.ToLower(name)).Delete(&TrackImpl{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTrackNotFound
+	}
+
+	return nil
+}
+
+func (repository trackRepositoryImpl) Promote(user, author, track string, expireAt *time.Time) (Promotion, error) {
+	return repository.move(user, author, track, 1, expireAt)
+}
+
+func (repository trackRepositoryImpl) Demote(user, author, track string) (Promotion, error) {
+	return repository.move(user, author, track, -1, nil)
+}
+
+// Swap the highest grant of the user on the track for the one step away.
+func (repository trackRepositoryImpl) move(user, author, name string, step int, expireAt *time.Time) (Promotion, error) {
+	track, err := repository.FindTrack(name)
+
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	ladder := track.GetGroups()
+	promotion := Promotion{
+		Track: track.GetName(),
+	}
+
+	err = repository.db.Transaction(func(tx *gorm.DB) error {
+		var grants []data.GroupInfo
+
+		// Locked so concurrent promotions of the account cannot swap the same grant twice.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user = ?", user).Find(&grants).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		position := -1
+		var current data.GroupInfo
+
+		for _, grant := range grants {
+			if !grant.ExpireAt.After(now) {
+				continue
+			}
+
+			if index := ladder.Index(grant.Group); index > position {
+				position, current = index, grant
+			}
+		}
+
+		if step < 0 && position < 0 {
+			return ErrNotOnTrack
+		}
+
+		target := position + step
+
+		if target >= len(ladder) {
+			return ErrTrackEnd
+		}
+
+		var authored []data.GroupInfo
+
+		if err := tx.Where("user = ?", author).Find(&authored).Error; err != nil {
+			return err
+		}
+
+		rank, err := repository.groups.PrimaryGroup(authored, data.GroupContext{})
+
+		if err != nil {
+			return err
+		}
+
+		// The highest group touched is the target on promotions and the current one on demotions.
+		highest := target
+
+		if position > highest {
+			highest = position
+		}
+
+		weight, err := repository.weightOf(ladder[highest])
+
+		if err != nil {
+			return err
+		}
+
+		limit, err := repository.weightOf(rank)
+
+		if err != nil {
+			return err
+		}
+
+		if weight > limit {
+			return ErrOutranked
+		}
+
+		if position >= 0 {
+			// Grants of the group on other contexts are kept.
+			if err := tx.Where("user = ? AND role = ? AND context = ?", user, current.Group, current.Context.String()).Delete(&data.GroupInfo{}).Error; err != nil {
+				return err
+			}
+
+			promotion.From = &current
+		}
+
+		if target >= 0 {
+			grant := CreateGroupInfo(user, author, ladder[target], Permanent, now)
+
+			// Promotions keep the expiry and context of the grant they replace.
+			if position >= 0 {
+				grant.ExpireAt = current.ExpireAt
+				grant.Context = current.Context
+			}
+
+			if expireAt != nil {
+				grant.ExpireAt = *expireAt
+			}
+
+			// Demotions may land on a group the account already holds on the context, which is kept as is.
+			for _, held := range grants {
+				if held.Group == grant.Group && held.Context.String() == grant.Context.String() && held.ExpireAt.After(now) {
+					grant = held
+					promotion.To = &grant
+				}
+			}
+
+			if promotion.To == nil {
+				// Expired grants of the group on the context may be waiting for the sweeper.
+				err := tx.Where("user = ? AND role = ? AND context = ? AND expire_at <= ?", user, grant.Group, grant.Context.String(), now).
+					Delete(&data.GroupInfo{}).Error
+
+				if err != nil {
+					return err
+				}
+
+				if err := tx.Create(&grant).Error; err != nil {
+					return err
+				}
+
+				promotion.To = &grant
+			}
+		}
+
+		var metadata data.MetadataSet
+
+		err = tx.Where("user = ?", user).First(&metadata).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// The current group follows the track only when it was the replaced group.
+		if promotion.From == nil || len(metadata.CurrentGroup) > 0 && metadata.CurrentGroup != promotion.From.Group {
+			return nil
+		}
+
+		if promotion.To != nil {
+			promotion.CurrentGroup = promotion.To.Group
+		} else {
+			var remaining []data.GroupInfo
+
+			if err := tx.Where("user = ?", user).Find(&remaining).Error; err != nil {
+				return err
+			}
+
+			if promotion.CurrentGroup, err = repository.groups.PrimaryGroup(remaining, data.GroupContext{}); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&data.MetadataSet{}).Where("user = ?", user).Update("current_group", promotion.CurrentGroup).Error
+	})
+
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	return promotion, nil
+}
+
+// Uppercase the groups and ensure every one is on the registry, only once.
+func (repository trackRepositoryImpl) ladderOf(groups data.GroupLadder) (data.GroupLadder, error) {
+	if len(groups) == 0 {
+		return nil, ErrInvalidLadder
+	}
+
+	ladder := data.GroupLadder{}
+
+	for _, name := range groups {
+		group, err := repository.groups.FindGroup(string(name))
+
+		if errors.Is(err, ErrGroupNotFound) {
+			return nil, ErrInvalidLadder
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ladder.Index(group.GetName()) >= 0 {
+			return nil, ErrInvalidLadder
+		}
+
+		ladder = append(ladder, group.GetName())
+	}
+
+	if len(ladder.String()) > 255 {
+		return nil, ErrInvalidLadder
+	}
+
+	return ladder, nil
+}
+
+// Weight of the group on the registry, groups missing from it weigh nothing.
+func (repository trackRepositoryImpl) weightOf(name data.GroupType) (int, error) {
+	group, err := repository.groups.FindGroup(string(name))
+
+	if errors.Is(err, ErrGroupNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return group.GetWeight(), nil
+}
+
+func CreateTrackRepository(db *gorm.DB, groups GroupRepository) TrackRepository {
+	return trackRepositoryImpl{
+		db:     db,
+		groups: groups,
+	}
+}
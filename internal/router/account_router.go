@@ -3,13 +3,14 @@ package router
 import (
 	"errors"
 	"fmt"
-	"math"
 	"reflect"
+	"strings"
 	"time"
 
 	. "github.com/luiz-otavio/galax/internal/impl"
 	"github.com/rs/zerolog/log"
 
+	"github.com/luiz-otavio/galax/internal/mojang"
 	"github.com/luiz-otavio/galax/internal/repository"
 	"github.com/luiz-otavio/galax/internal/util"
 	"github.com/luiz-otavio/galax/internal/worker"
@@ -28,26 +29,50 @@ type AccountRouter interface {
 	UpdateCash(ctx *fiber.Ctx) error
 	AddCash(ctx *fiber.Ctx) error
 	TakeCash(ctx *fiber.Ctx) error
+	TransferCash(ctx *fiber.Ctx) error
+	CashHistory(ctx *fiber.Ctx) error
+	Leaderboard(ctx *fiber.Ctx) error
+	Permissions(ctx *fiber.Ctx) error
+	RedeemCoupon(ctx *fiber.Ctx) error
 	UpdateMetadata(ctx *fiber.Ctx) error
 	AddGroup(ctx *fiber.Ctx) error
 	RemoveGroup(ctx *fiber.Ctx) error
+	Promote(ctx *fiber.Ctx) error
+	Demote(ctx *fiber.Ctx) error
 }
 
 type accountRouterImpl struct {
-	db     *gorm.DB
-	cache  repository.RedisRepository
-	worker worker.Worker
+	db            *gorm.DB
+	cache         repository.RedisRepository
+	ledger        repository.LedgerRepository
+	currencies    repository.CurrencyRepository
+	coupons       repository.CouponRepository
+	leaderboard   repository.LeaderboardRepository
+	permissions   repository.PermissionRepository
+	tracks        repository.TrackRepository
+	responses     repository.IdempotencyRepository
+	worker        worker.Worker
+	sessionServer mojang.SessionServer
 }
 
 func (r *accountRouterImpl) TakeEndpoints(router fiber.Router) {
-	router.Put("/create", r.CreateAccount)
-	router.Get("/search", r.SearchAccount)
-	router.Patch("/metadata", r.UpdateMetadata)
-	router.Delete("/group", r.RemoveGroup)
-	router.Post("/group", r.AddGroup)
-	router.Patch("/cash/update", r.UpdateCash)
-	router.Patch("/cash/sum", r.AddCash)
-	router.Patch("/cash/take", r.TakeCash)
+	idempotent := Idempotent(r.responses)
+
+	router.Put("/create", RequireScope(data.ACCOUNT_WRITE), idempotent, r.CreateAccount)
+	router.Get("/search", RequireScope(data.ACCOUNT_READ), r.SearchAccount)
+	router.Patch("/metadata", RequireScope(data.ACCOUNT_WRITE), idempotent, r.UpdateMetadata)
+	router.Delete("/group", RequireScope(data.GROUP_WRITE), idempotent, r.RemoveGroup)
+	router.Post("/group", RequireScope(data.GROUP_WRITE), idempotent, r.AddGroup)
+	router.Post("/promote", RequireScope(data.GROUP_WRITE), idempotent, r.Promote)
+	router.Post("/demote", RequireScope(data.GROUP_WRITE), idempotent, r.Demote)
+	router.Patch("/cash/update", RequireScope(data.CASH_WRITE), idempotent, r.UpdateCash)
+	router.Patch("/cash/sum", RequireScope(data.CASH_WRITE), idempotent, r.AddCash)
+	router.Patch("/cash/take", RequireScope(data.CASH_WRITE), idempotent, r.TakeCash)
+	router.Post("/cash/transfer", RequireScope(data.CASH_WRITE), idempotent, r.TransferCash)
+	router.Get("/cash/history", RequireScope(data.ACCOUNT_READ), r.CashHistory)
+	router.Get("/leaderboard", RequireScope(data.ACCOUNT_READ), r.Leaderboard)
+	router.Get("/permissions", RequireScope(data.ACCOUNT_READ), r.Permissions)
+	router.Post("/redeem", RequireScope(data.COUPON_REDEEM), idempotent, r.RedeemCoupon)
 }
 
 func (r *accountRouterImpl) CreateAccount(ctx *fiber.Ctx) error {
@@ -69,52 +94,69 @@ func (r *accountRouterImpl) CreateAccount(ctx *fiber.Ctx) error {
 
 	util.DebugOutput("Income request for creating account with name '%s'", name)
 
-	if err := r.db.Where("username = ?", name).First(AccountImpl{}).Error; err == nil {
-		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"message": "Account already exists.",
+	accountType, ok := body["accountType"].(string)
+
+	if !ok || len(accountType) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Account type is required.",
 		})
 	}
 
-	uniqueId, ok := body["unique_id"].(string)
-
-	if !ok || len(uniqueId) == 0 {
-		uniqueId = util.OfflinePlayerUUID(name).
-			String()
-	}
+	targetType, err := util.ParseAccountType(accountType)
 
-	if len(uniqueId) < 32 || len(uniqueId) > 36 {
+	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Unique id.",
+			"message": "Account type is invalid.",
 		})
 	}
 
-	var account AccountImpl
+	var premiumId string
 
-	if err := r.db.Where("unique_id = ?", uniqueId).First(&account).Error; err == nil {
-		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"message": "Account already exists.",
-		})
-	}
+	// Premium is never trusted from the caller, it must be proven against the session server.
+	if targetType == data.PREMIUM {
+		serverId, _ := body["server_id"].(string)
+		address, _ := body["address"].(string)
 
-	if util.EnsureUUID(uniqueId) {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Unique id is not valid.",
-		})
+		var profile mojang.Profile
+
+		if len(serverId) > 0 {
+			profile, err = r.sessionServer.HasJoined(name, serverId, address)
+		}
+
+		// Claims without a server id cannot be verified, so they fail verification like any other.
+		if len(serverId) == 0 || errors.Is(err, mojang.ErrNotVerified) {
+			util.DebugOutput("Premium verification failed for '%s', creating as cracked.", name)
+			targetType = data.CRACKED
+		} else if err != nil {
+			log.Error().Err(err).Msg("Could not reach the session server.")
+
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"message": "Could not verify premium account.",
+			})
+		} else {
+			premiumId = profile.Id
+			name = profile.Name
+		}
 	}
 
-	accountType, ok := body["accountType"].(string)
+	uniqueId := premiumId
 
-	if !ok || len(accountType) == 0 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Account type is required.",
+	if targetType == data.CRACKED {
+		uniqueId = util.OfflinePlayerUUID(name).String()
+	}
+
+	// Checked once verification settled the name, premium accounts take the one from the session server.
+	if err := r.db.Where("username = ?", name).First(&AccountImpl{}).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Account already exists.",
 		})
 	}
 
-	targetType, err := util.ParseAccountType(accountType)
+	var account AccountImpl
 
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Account type is invalid.",
+	if err := r.db.Where("unique_id = ?", uniqueId).First(&account).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Account already exists.",
 		})
 	}
 
@@ -124,6 +166,7 @@ func (r *accountRouterImpl) CreateAccount(ctx *fiber.Ctx) error {
 		},
 
 		AccountType: targetType,
+		PremiumId:   premiumId,
 
 		Name: name,
 		Cash: 0,
@@ -148,6 +191,9 @@ func (r *accountRouterImpl) CreateAccount(ctx *fiber.Ctx) error {
 	util.DebugOutput("Account created for %s", name)
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Account created.",
+
+		"unique_id":   account.GetUniqueId(),
+		"accountType": account.GetAccountType(),
 	})
 }
 
@@ -191,23 +237,14 @@ func (r *accountRouterImpl) UpdateCash(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if _, err = util.EnsureType(body["cash"], reflect.Float64, "cash isn't a number."); err != nil {
+	cash, err := util.ParseCash(body["cash"])
+
+	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Cash isn't a number.",
 		})
 	}
 
-	// Parse cash to int32
-	cash := int32(
-		math.Round(
-			body["cash"].(float64),
-		),
-	)
-
-	if cash < 0 {
-		cash = 0
-	}
-
 	account := r.cache.LoadAccount(uniqueId)
 
 	if account == nil {
@@ -220,19 +257,37 @@ func (r *accountRouterImpl) UpdateCash(ctx *fiber.Ctx) error {
 		}
 	}
 
-	account.SetCash(cash)
+	currency, ok := r.CurrencyOf(body["currency"])
 
-	r.worker.Do(func(d *gorm.DB) {
-		d.Model(AccountImpl{}).
-			Where("unique_id = ?", uniqueId).
-			Update("cash", account.GetCash())
-	})
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
+	}
 
-	r.cache.UpdateCash(uniqueId, cash)
+	entry, err := r.LedgerEntryOf(ctx, body, "update")
 
-	util.DebugOutput("Updated cash for user %s with %d", account.GetUniqueId(), account.GetCash())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Reason, actor and idempotency key must have at most 64 characters.",
+		})
+	}
+
+	transaction, err := r.ledger.Apply(account.GetUniqueId(), currency, func(balance int64) (int64, error) {
+		return cash, nil
+	}, entry)
+
+	if err != nil {
+		return r.RejectLedger(ctx, err)
+	}
+
+	r.cache.UpdateWallet(uniqueId, currency, transaction.GetBalance())
+
+	util.DebugOutput("Updated cash for user %s with %d", account.GetUniqueId(), transaction.GetBalance())
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Account updated.",
+		"message":  "Account updated.",
+		"cash":     transaction.GetBalance(),
+		"currency": currency,
 	})
 }
 
@@ -253,18 +308,19 @@ func (r *accountRouterImpl) AddCash(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if _, err = util.EnsureType(body["cash"], reflect.Float64, "cash isn't a number."); err != nil {
+	cash, err := util.ParseCash(body["cash"])
+
+	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Cash isn't a number.",
 		})
 	}
 
-	// Parse cash to int32
-	cash := int32(
-		math.Round(
-			body["cash"].(float64),
-		),
-	)
+	if cash <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cash must be positive.",
+		})
+	}
 
 	account := r.cache.LoadAccount(uniqueId)
 
@@ -278,23 +334,37 @@ func (r *accountRouterImpl) AddCash(ctx *fiber.Ctx) error {
 		}
 	}
 
-	if account.GetCash()+cash < 0 {
-		cash = -account.GetCash()
+	currency, ok := r.CurrencyOf(body["currency"])
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
 	}
 
-	account.AddCash(cash)
+	entry, err := r.LedgerEntryOf(ctx, body, "sum")
 
-	r.worker.Do(func(d *gorm.DB) {
-		d.Model(AccountImpl{}).
-			Where("unique_id = ?", uniqueId).
-			Update("cash", account.GetCash())
-	})
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Reason, actor and idempotency key must have at most 64 characters.",
+		})
+	}
+
+	transaction, err := r.ledger.Apply(account.GetUniqueId(), currency, func(balance int64) (int64, error) {
+		return repository.AddBalance(balance, cash)
+	}, entry)
+
+	if err != nil {
+		return r.RejectLedger(ctx, err)
+	}
 
-	r.cache.AddCash(uniqueId, account.GetCash())
+	r.cache.UpdateWallet(uniqueId, currency, transaction.GetBalance())
 
-	util.DebugOutput("Added cash for user %s with %d", account.GetUniqueId(), account.GetCash())
+	util.DebugOutput("Added cash for user %s with %d", account.GetUniqueId(), transaction.GetAmount())
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Account updated.",
+		"message":  "Account updated.",
+		"cash":     transaction.GetBalance(),
+		"currency": currency,
 	})
 }
 
@@ -315,18 +385,19 @@ func (r *accountRouterImpl) TakeCash(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if _, err = util.EnsureType(body["cash"], reflect.Float64, "cash isn't a number."); err != nil {
+	cash, err := util.ParseCash(body["cash"])
+
+	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Cash isn't a number.",
 		})
 	}
 
-	// Parse cash to int32
-	cash := int32(
-		math.Round(
-			body["cash"].(float64),
-		),
-	)
+	if cash <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cash must be positive.",
+		})
+	}
 
 	account := r.cache.LoadAccount(uniqueId)
 
@@ -340,26 +411,417 @@ func (r *accountRouterImpl) TakeCash(ctx *fiber.Ctx) error {
 		}
 	}
 
-	if account.GetCash()-cash < 0 {
-		cash = account.GetCash()
+	currency, ok := r.CurrencyOf(body["currency"])
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
 	}
 
-	account.TakeCash(cash)
+	entry, err := r.LedgerEntryOf(ctx, body, "take")
 
-	r.worker.Do(func(d *gorm.DB) {
-		d.Model(AccountImpl{}).
-			Where("unique_id = ?", uniqueId).
-			Update("cash", account.GetCash())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Reason, actor and idempotency key must have at most 64 characters.",
+		})
+	}
+
+	transaction, err := r.ledger.Apply(account.GetUniqueId(), currency, func(balance int64) (int64, error) {
+		return repository.SubBalance(balance, cash)
+	}, entry)
+
+	if err != nil {
+		return r.RejectLedger(ctx, err)
+	}
+
+	r.cache.UpdateWallet(uniqueId, currency, transaction.GetBalance())
+
+	util.DebugOutput("Taken cash for user %s with %d", account.GetUniqueId(), -transaction.GetAmount())
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Account updated.",
+		"cash":     transaction.GetBalance(),
+		"currency": currency,
 	})
+}
+
+func (r *accountRouterImpl) TransferCash(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
 
-	r.cache.TakeCash(uniqueId, account.GetCash())
+	from, err := r.FilterUUIDByValue(fmt.Sprint(body["from"]))
 
-	util.DebugOutput("Taken cash for user %s with %d", account.GetUniqueId(), account.GetCash())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "From is not valid.",
+		})
+	}
+
+	to, err := r.FilterUUIDByValue(fmt.Sprint(body["to"]))
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "To is not valid.",
+		})
+	}
+
+	if from == to {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cannot transfer cash to the same account.",
+		})
+	}
+
+	util.DebugOutput("Income request for transferring cash from %s to %s.", from, to)
+
+	cash, err := util.ParseCash(body["cash"])
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cash isn't a number.",
+		})
+	}
+
+	if cash <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cash must be positive.",
+		})
+	}
+
+	// Both accounts must be on cache, so the balances below land on complete hashes.
+	for _, uniqueId := range []string{from, to} {
+		if r.cache.LoadAccount(uniqueId) == nil && r.RetrieveByDatabase(uniqueId) == nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Account not found.",
+			})
+		}
+	}
+
+	currency, ok := r.CurrencyOf(body["currency"])
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
+	}
+
+	entry, err := r.LedgerEntryOf(ctx, body, "transfer")
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Reason, actor and idempotency key must have at most 64 characters.",
+		})
+	}
+
+	debit, credit, err := r.ledger.Transfer(from, to, currency, cash, entry)
+
+	if err != nil {
+		return r.RejectLedger(ctx, err)
+	}
+
+	r.cache.TransferCash(currency, from, debit.GetBalance(), to, credit.GetBalance())
+
+	util.DebugOutput("Transferred %d cash from %s to %s", cash, from, to)
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Account updated.",
+		"message":  "Cash transferred.",
+		"currency": currency,
+
+		"from": fiber.Map{
+			"unique_id": from,
+			"cash":      debit.GetBalance(),
+		},
+		"to": fiber.Map{
+			"unique_id": to,
+			"cash":      credit.GetBalance(),
+		},
+	})
+}
+
+func (r *accountRouterImpl) CashHistory(ctx *fiber.Ctx) error {
+	uniqueId, err := r.FilterUUIDByQuery(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	util.DebugOutput("Income request for cash history of user %s.", uniqueId)
+
+	page, err := util.ParseInt(ctx.Query("page"), 1)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Page isn't a number.",
+		})
+	}
+
+	size, err := util.ParseInt(ctx.Query("size"), 20)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Size isn't a number.",
+		})
+	}
+
+	if page < 1 || size < 1 || size > 100 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Page must be positive and size between 1 and 100.",
+		})
+	}
+
+	currency, ok := r.CurrencyOf(ctx.Query("currency"))
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
+	}
+
+	transactions, total, err := r.ledger.History(uniqueId, currency, (page-1)*size, size)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not load cash history.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not load cash history.",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"page":         page,
+		"size":         size,
+		"total":        total,
+		"currency":     currency,
+		"transactions": transactions,
 	})
 }
 
+func (r *accountRouterImpl) Leaderboard(ctx *fiber.Ctx) error {
+	page, err := util.ParseInt(ctx.Query("page"), 1)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Page isn't a number.",
+		})
+	}
+
+	size, err := util.ParseInt(ctx.Query("size"), 10)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Size isn't a number.",
+		})
+	}
+
+	if page < 1 || size < 1 || size > 100 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Page must be positive and size between 1 and 100.",
+		})
+	}
+
+	currency, ok := r.CurrencyOf(ctx.Query("currency"))
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency is not valid.",
+		})
+	}
+
+	entries, total, err := r.leaderboard.Top(currency, int64((page-1)*size), int64(size))
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not load leaderboard.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not load leaderboard.",
+		})
+	}
+
+	result := fiber.Map{
+		"page":     page,
+		"size":     size,
+		"total":    total,
+		"currency": currency,
+		"entries":  entries,
+	}
+
+	// The rank of a player may be asked along with the page, such as to show it below the top.
+	if len(ctx.Query("id")) > 0 {
+		uniqueId, err := r.FilterUUIDByQuery(ctx)
+
+		// The response was already written when the player is not valid.
+		if err != nil || len(uniqueId) == 0 {
+			return err
+		}
+
+		entry, ranked, err := r.leaderboard.Rank(currency, uniqueId)
+
+		if err != nil {
+			log.Error().Err(err).Msg("Could not load leaderboard rank.")
+
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Could not load leaderboard.",
+			})
+		}
+
+		if ranked {
+			result["player"] = entry
+		} else {
+			result["player"] = nil
+		}
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+func (r *accountRouterImpl) Permissions(ctx *fiber.Ctx) error {
+	uniqueId, err := r.FilterUUIDByQuery(ctx)
+
+	// The response was already written when the player is not valid.
+	if err != nil || len(uniqueId) == 0 {
+		return err
+	}
+
+	account := r.cache.LoadAccount(uniqueId)
+
+	if account == nil {
+		account = r.RetrieveByDatabase(uniqueId)
+
+		if account == nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Account not found.",
+			})
+		}
+	}
+
+	context, err := data.ParseGroupContext(ctx.Query("context"))
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Context is not valid: " + err.Error() + ".",
+		})
+	}
+
+	permissions, err := r.permissions.Resolve(account, context)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not resolve permissions.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not resolve permissions.",
+		})
+	}
+
+	result := fiber.Map{
+		"unique_id":   uniqueId,
+		"context":     context,
+		"primary":     permissions.Primary,
+		"groups":      permissions.Groups,
+		"permissions": permissions.Permissions,
+	}
+
+	// A single node may be checked, such as by servers gating a command.
+	if node := ctx.Query("node"); len(node) > 0 {
+		result["node"] = node
+		result["allowed"] = permissions.Allows(node)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+func (r *accountRouterImpl) RedeemCoupon(ctx *fiber.Ctx) error {
+	uniqueId, err := r.FilterUUIDByQuery(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	util.DebugOutput("Income request to redeem coupon for user %s.", uniqueId)
+
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	code, ok := body["code"].(string)
+
+	if !ok || len(code) == 0 || len(code) > 64 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Code is required.",
+		})
+	}
+
+	account := r.cache.LoadAccount(uniqueId)
+
+	if account == nil {
+		account = r.RetrieveByDatabase(uniqueId)
+
+		if account == nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Account not found.",
+			})
+		}
+	}
+
+	entry, err := r.LedgerEntryOf(ctx, body, repository.CouponReason)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Reason, actor and idempotency key must have at most 64 characters.",
+		})
+	}
+
+	redemption, transaction, group, err := r.coupons.Redeem(code, account.GetUniqueId(), entry)
+
+	switch {
+	case errors.Is(err, repository.ErrCouponNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Coupon not found.",
+		})
+	case errors.Is(err, repository.ErrCouponExpired):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Coupon has expired.",
+		})
+	case errors.Is(err, repository.ErrCouponExhausted):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Coupon has no redemptions left.",
+		})
+	case errors.Is(err, repository.ErrCouponLimit):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Coupon was already redeemed by the account.",
+		})
+	case err != nil:
+		return r.RejectLedger(ctx, err)
+	}
+
+	result := fiber.Map{
+		"message":    "Coupon redeemed.",
+		"redemption": redemption,
+	}
+
+	if transaction != nil {
+		r.cache.UpdateWallet(account.GetUniqueId(), transaction.GetCurrency(), transaction.GetBalance())
+
+		result["cash"] = transaction.GetBalance()
+		result["currency"] = transaction.GetCurrency()
+	}
+
+	if group != nil {
+		r.cache.AddGroup(account, *group)
+
+		result["group"] = group
+	}
+
+	util.DebugOutput("Redeemed coupon %s for user %s", redemption.GetCoupon(), account.GetUniqueId())
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
 func (r *accountRouterImpl) UpdateMetadata(ctx *fiber.Ctx) error {
 	uniqueId, err := r.FilterUUIDByQuery(ctx)
 
@@ -480,12 +942,6 @@ func (r *accountRouterImpl) AddGroup(ctx *fiber.Ctx) error {
 			})
 		}
 
-		if !account.HasGroupSet(groupType) {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Account does not have group set: '" + key + "'.",
-			})
-		}
-
 		info, ok := group.(map[string]interface{})
 
 		if !ok || info == nil {
@@ -555,9 +1011,25 @@ func (r *accountRouterImpl) AddGroup(ctx *fiber.Ctx) error {
 			})
 		}
 
+		context, err := r.ContextOf(info["context"])
+
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Context is not valid: " + err.Error() + ".",
+			})
+		}
+
+		// The same group may be granted on other contexts, only the exact grant is a duplicate.
+		if account.HasGroupSet(groupType, context) {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Account already has group set: '" + key + "' on context '" + context.String() + "'.",
+			})
+		}
+
 		groupInfo := data.GroupInfo{
-			Group:  groupType,
-			Author: target,
+			Group:   groupType,
+			Author:  target,
+			Context: context,
 
 			User: account.GetUniqueId(),
 
@@ -570,7 +1042,7 @@ func (r *accountRouterImpl) AddGroup(ctx *fiber.Ctx) error {
 		})
 
 		util.DebugOutput(
-			"Added group info for account %s, group %s, author %s, expire at %d, created at %d.",
+			"Added group info for account %s, group %s, author %s, expire at %s, created at %s.",
 			account.GetUniqueId(),
 			groupType,
 			target,
@@ -604,7 +1076,7 @@ func (r *accountRouterImpl) RemoveGroup(ctx *fiber.Ctx) error {
 		})
 	}
 
-	groups, ok := body["group_set"].([]string)
+	groups, ok := body["group_set"].([]interface{})
 
 	if !ok {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -612,6 +1084,15 @@ func (r *accountRouterImpl) RemoveGroup(ctx *fiber.Ctx) error {
 		})
 	}
 
+	// Grants are removed on a single context, the one without context when omitted.
+	context, err := r.ContextOf(body["context"])
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Context is not valid: " + err.Error() + ".",
+		})
+	}
+
 	account := r.cache.LoadAccount(uniqueId)
 
 	if account == nil {
@@ -624,7 +1105,15 @@ func (r *accountRouterImpl) RemoveGroup(ctx *fiber.Ctx) error {
 		}
 	}
 
-	for _, key := range groups {
+	for _, value := range groups {
+		key, ok := value.(string)
+
+		if !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Group set must only have group names.",
+			})
+		}
+
 		groupType, err := util.ParseGroupType(key)
 
 		if err != nil {
@@ -633,18 +1122,21 @@ func (r *accountRouterImpl) RemoveGroup(ctx *fiber.Ctx) error {
 			})
 		}
 
-		if !account.HasGroupSet(groupType) {
+		if !account.HasGroupSet(groupType, context) {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Account does not have group set: '" + key + "'.",
+				"message": "Account does not have group set: '" + key + "' on context '" + context.String() + "'.",
 			})
 		}
 
 		util.DebugOutput("Removing group with %s name from %s account.", key, uniqueId)
 
-		account.RemoveGroup(groupType)
+		account.RemoveGroup(groupType, context)
+
+		// Drops the cached permissions of the account along with the group.
+		r.cache.RemoveGroup(account, data.GroupInfo{Group: groupType, Context: context})
 
 		r.worker.Do(func(d *gorm.DB) {
-			d.Where("user = ? AND role = ?", uniqueId, key).Delete(&data.GroupInfo{})
+			d.Where("user = ? AND role = ? AND context = ?", uniqueId, groupType, context.String()).Delete(&data.GroupInfo{})
 		})
 	}
 
@@ -654,19 +1146,223 @@ func (r *accountRouterImpl) RemoveGroup(ctx *fiber.Ctx) error {
 	})
 }
 
-func (r *accountRouterImpl) FilterUUIDByQuery(ctx *fiber.Ctx) (string, error) {
-	id := ctx.Query("id")
+func (r *accountRouterImpl) Promote(ctx *fiber.Ctx) error {
+	return r.MoveOnTrack(ctx, true)
+}
 
-	if !util.EnsureUUID(id) {
-		new, err := r.FilterByUsername(id)
+func (r *accountRouterImpl) Demote(ctx *fiber.Ctx) error {
+	return r.MoveOnTrack(ctx, false)
+}
+
+// Promote or demote the account one group on the track given on body, on behalf of the author.
+func (r *accountRouterImpl) MoveOnTrack(ctx *fiber.Ctx, promote bool) error {
+	uniqueId, err := r.FilterUUIDByQuery(ctx)
+
+	if err != nil || len(uniqueId) == 0 {
+		return err
+	}
+
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	track, ok := body["track"].(string)
+
+	if !ok || len(track) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Track is required.",
+		})
+	}
+
+	author, ok := body["author"].(string)
+
+	if !ok || len(author) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Author is required.",
+		})
+	}
+
+	author, err = r.FilterUUIDByValue(author)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Author is not valid.",
+		})
+	}
+
+	var expireAt *time.Time
+
+	if body["expire_at"] != nil && promote {
+		unix, err := util.ParseInt64(body["expire_at"], 0)
 
 		if err != nil {
-			return "", ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Id is not valid.",
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Expire at is not valid.",
+			})
+		}
+
+		target := time.Unix(unix, 0)
+		expireAt = &target
+	}
+
+	account := r.cache.LoadAccount(uniqueId)
+
+	if account == nil {
+		account = r.RetrieveByDatabase(uniqueId)
+
+		if account == nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Account not found.",
 			})
 		}
+	}
+
+	var promotion repository.Promotion
+
+	if promote {
+		promotion, err = r.tracks.Promote(uniqueId, author, track, expireAt)
+	} else {
+		promotion, err = r.tracks.Demote(uniqueId, author, track)
+	}
+
+	if err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	if promotion.From != nil {
+		r.cache.RemoveGroup(account, *promotion.From)
+	}
+
+	if promotion.To != nil {
+		r.cache.AddGroup(account, *promotion.To)
+	}
+
+	if len(promotion.CurrentGroup) > 0 {
+		r.cache.UpdateMetadata(uniqueId, "current_group", string(promotion.CurrentGroup))
+	}
+
+	util.DebugOutput("Moved account %s on track %s by %s.", uniqueId, promotion.Track, author)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Account updated.",
+
+		"promotion": promotion,
+	})
+}
+
+// Parse the context of a grant, given either as an object of strings or on the "key:value,key:value" form.
+func (r *accountRouterImpl) ContextOf(value interface{}) (data.GroupContext, error) {
+	switch target := value.(type) {
+	case nil:
+		return data.GroupContext{}, nil
+	case string:
+		return data.ParseGroupContext(target)
+	case map[string]interface{}:
+		context := data.GroupContext{}
+
+		for key, entry := range target {
+			text, ok := entry.(string)
+
+			if !ok {
+				return nil, errors.New("context values must be strings")
+			}
+
+			context[strings.ToLower(key)] = strings.ToLower(text)
+		}
+
+		return context, context.Validate()
+	}
+
+	return nil, errors.New("context must be an object")
+}
+
+// Describe the change for the ledger, the reason and actor may be given on body.
+func (r *accountRouterImpl) LedgerEntryOf(ctx *fiber.Ctx, body map[string]interface{}, reason string) (repository.LedgerEntry, error) {
+	entry := repository.LedgerEntry{
+		Reason: reason,
+
+		IdempotencyKey: ctx.Get(IdempotencyHeader),
+	}
+
+	if target, ok := body["reason"].(string); ok && len(target) > 0 {
+		entry.Reason = target
+	}
+
+	if actor, ok := body["actor"].(string); ok {
+		entry.Actor = actor
+	}
+
+	if key, ok := APIKeyOf(ctx); ok {
+		entry.Source = key.GetName()
+	}
+
+	if len(entry.Reason) > 64 || len(entry.Actor) > 64 || len(entry.IdempotencyKey) > 64 {
+		return entry, errors.New("reason, actor and idempotency key must have at most 64 characters")
+	}
+
+	return entry, nil
+}
 
-		return new, nil
+// Resolve the currency given by the caller, falling back to the default one when absent.
+func (r *accountRouterImpl) CurrencyOf(value interface{}) (string, bool) {
+	if value == nil || value == "" {
+		return r.cache.GetConfig().GetDefaultCurrency(), true
+	}
+
+	currency, ok := value.(string)
+
+	if !ok || !r.currencies.HasCurrency(currency) {
+		return "", false
+	}
+
+	return currency, true
+}
+
+func (r *accountRouterImpl) RejectLedger(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Account not found.",
+		})
+	}
+
+	if errors.Is(err, repository.ErrInsufficientFunds) {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Insufficient funds.",
+		})
+	}
+
+	if errors.Is(err, repository.ErrOutOfBounds) {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Balance would leave the limits of the currency.",
+		})
+	}
+
+	log.Error().Err(err).Msg("Could not update cash.")
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"message": "Could not update cash.",
+	})
+}
+
+func (r *accountRouterImpl) FilterUUIDByQuery(ctx *fiber.Ctx) (string, error) {
+	id, err := r.FilterUUIDByValue(ctx.Query("id"))
+
+	if err != nil {
+		return "", ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Id is not valid.",
+		})
+	}
+
+	return id, nil
+}
+
+// Resolve either an unique id or an username to the unique id.
+func (r *accountRouterImpl) FilterUUIDByValue(id string) (string, error) {
+	if !util.EnsureUUID(id) {
+		return r.FilterByUsername(id)
 	}
 
 	return id, nil
@@ -702,10 +1398,18 @@ func (r *accountRouterImpl) FilterByUsername(username string) (string, error) {
 	return unique_id, nil
 }
 
-func CreateAccountRouter(db *gorm.DB, repository repository.RedisRepository, worker worker.Worker) AccountRouter {
+func CreateAccountRouter(db *gorm.DB, repository repository.RedisRepository, ledger repository.LedgerRepository, currencies repository.CurrencyRepository, coupons repository.CouponRepository, leaderboard repository.LeaderboardRepository, permissions repository.PermissionRepository, tracks repository.TrackRepository, responses repository.IdempotencyRepository, worker worker.Worker, sessionServer mojang.SessionServer) AccountRouter {
 	return &accountRouterImpl{
-		db:     db,
-		cache:  repository,
-		worker: worker,
+		db:            db,
+		cache:         repository,
+		ledger:        ledger,
+		currencies:    currencies,
+		coupons:       coupons,
+		leaderboard:   leaderboard,
+		permissions:   permissions,
+		tracks:        tracks,
+		responses:     responses,
+		worker:        worker,
+		sessionServer: sessionServer,
 	}
 }
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/keyauth/v2"
+	"github.com/luiz-otavio/galax/internal/impl"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/data"
+	"github.com/luiz-otavio/galax/pkg/signature"
+)
+
+const apiKeyLocal = "galax-api-key"
+
+// Middleware resolving the API key of every request, the legacy key is accepted with every scope.
+// Requests carrying a signature are verified against the key signing secret instead.
+func RequireAPIKey(keys repository.APIKeyRepository, legacy string, skew time.Duration) fiber.Handler {
+	legacyKey := impl.CreateAPIKey("", "legacy", "", "", []data.Scope{data.ALL_SCOPES}, []string{}, nil)
+
+	bearer := keyauth.New(keyauth.Config{
+		Validator: func(ctx *fiber.Ctx, plain string) (bool, error) {
+			util.DebugOutput("Income request from %s", ctx.IP())
+
+			if len(legacy) > 0 && subtle.ConstantTimeCompare([]byte(plain), []byte(legacy)) == 1 {
+				ctx.Locals(apiKeyLocal, legacyKey)
+				return true, nil
+			}
+
+			key := keys.FindKey(plain)
+
+			if !AcceptsAPIKey(ctx, key) {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+
+			// Signing keys must never travel as a bare bearer token.
+			if key.RequiresSigning() {
+				util.DebugOutput("API key %s requires signed requests", key.GetName())
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+
+			ctx.Locals(apiKeyLocal, key)
+
+			return true, nil
+		},
+	})
+
+	return func(ctx *fiber.Ctx) error {
+		if len(ctx.Get(signature.HeaderSignature)) == 0 {
+			return bearer(ctx)
+		}
+
+		key := keys.FindKeyByName(ctx.Get(signature.HeaderKey))
+
+		if !AcceptsAPIKey(ctx, key) || !key.RequiresSigning() {
+			return rejectSignature(ctx, "Invalid or missing API key")
+		}
+
+		timestamp, err := strconv.ParseInt(ctx.Get(signature.HeaderTimestamp), 10, 64)
+
+		if err != nil {
+			return rejectSignature(ctx, "Invalid signature timestamp")
+		}
+
+		if drift := time.Since(time.Unix(timestamp, 0)); drift > skew || drift < -skew {
+			return rejectSignature(ctx, "Signature timestamp is outside the allowed window")
+		}
+
+		nonce := ctx.Get(signature.HeaderNonce)
+
+		if len(nonce) == 0 || len(nonce) > 64 {
+			return rejectSignature(ctx, "Invalid signature nonce")
+		}
+
+		canonical := signature.Canonical(ctx.Method(), ctx.OriginalURL(), ctx.Body(), timestamp, nonce)
+
+		if !signature.Verify(key.GetSigningSecret(), canonical, ctx.Get(signature.HeaderSignature)) {
+			return rejectSignature(ctx, "Invalid signature")
+		}
+
+		// The nonce must outlive the window on both sides, otherwise it could be replayed.
+		if !keys.UseNonce(key.GetName(), nonce, 2*skew) {
+			return rejectSignature(ctx, "Replayed request")
+		}
+
+		ctx.Locals(apiKeyLocal, key)
+
+		return ctx.Next()
+	}
+}
+
+// Whether the key exists and can be used by the request address.
+func AcceptsAPIKey(ctx *fiber.Ctx, key data.APIKey) bool {
+	if key == nil || key.IsRevoked() || key.IsExpired() {
+		return false
+	}
+
+	if !key.AllowsAddress(ctx.IP()) {
+		util.DebugOutput("API key %s is not allowed from %s", key.GetName(), ctx.IP())
+		return false
+	}
+
+	return true
+}
+
+func rejectSignature(ctx *fiber.Ctx, message string) error {
+	util.DebugOutput("Rejected signed request from %s: %s", ctx.IP(), message)
+
+	return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// Middleware for routes which requires the API key to hold the scope.
+func RequireScope(scope data.Scope) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key, ok := APIKeyOf(ctx)
+
+		if !ok || !key.HasScope(scope) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "API key is missing the '" + string(scope) + "' scope",
+			})
+		}
+
+		return ctx.Next()
+	}
+}
+
+// Retrieve the API key stored by RequireAPIKey.
+func APIKeyOf(ctx *fiber.Ctx) (data.APIKey, bool) {
+	key, ok := ctx.Locals(apiKeyLocal).(data.APIKey)
+
+	return key, ok
+}
@@ -1,13 +1,25 @@
 package router
 
 import (
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/notifier"
+	"github.com/luiz-otavio/galax/internal/policy"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/token"
+	"github.com/luiz-otavio/galax/internal/totp"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
 )
 
 type AuthRouter interface {
@@ -16,16 +28,57 @@ type AuthRouter interface {
 	Login(ctx *fiber.Ctx) error
 	Register(ctx *fiber.Ctx) error
 	ChangePassword(ctx *fiber.Ctx) error
+	Refresh(ctx *fiber.Ctx) error
+	Logout(ctx *fiber.Ctx) error
+	ListSessions(ctx *fiber.Ctx) error
+	RevokeSession(ctx *fiber.Ctx) error
+	RevokeSessions(ctx *fiber.Ctx) error
+	ClearLockout(ctx *fiber.Ctx) error
+	LoginTwoFactor(ctx *fiber.Ctx) error
+	EnrollTwoFactor(ctx *fiber.Ctx) error
+	ConfirmTwoFactor(ctx *fiber.Ctx) error
+	DisableTwoFactor(ctx *fiber.Ctx) error
+	RequestReset(ctx *fiber.Ctx) error
+	ConfirmReset(ctx *fiber.Ctx) error
+	PollMail(ctx *fiber.Ctx) error
 }
 
 type authRouterImpl struct {
-	db *gorm.DB
+	db       *gorm.DB
+	cache    repository.RedisRepository
+	config   *config.Config
+	issuer   token.Issuer
+	sessions repository.SessionRepository
+	lockout  repository.LockoutRepository
+	resets   repository.ResetRepository
+	notifier notifier.Notifier
+	policy   policy.Policy
 }
 
 func (r authRouterImpl) TakeEndpoints(router fiber.Router) {
-	router.Post("/login", r.Login)
-	router.Put("/register", r.Register)
-	router.Patch("/update", r.ChangePassword)
+	write := RequireScope(data.AUTH_WRITE)
+	admin := RequireScope(data.AUTH_ADMIN)
+
+	router.Post("/login", write, r.Login)
+	router.Put("/register", write, r.Register)
+	router.Patch("/update", write, r.ChangePassword)
+	router.Post("/refresh", write, r.Refresh)
+	router.Post("/logout", write, r.Logout)
+	router.Get("/sessions", admin, r.ListSessions)
+	router.Delete("/sessions", admin, r.RevokeSession)
+	router.Delete("/sessions/all", admin, r.RevokeSessions)
+	router.Delete("/lockout", admin, r.ClearLockout)
+
+	session := RequireSession(r.issuer, r.sessions)
+
+	router.Post("/login/totp", write, r.LoginTwoFactor)
+	router.Post("/totp/enroll", write, session, r.EnrollTwoFactor)
+	router.Post("/totp/confirm", write, session, r.ConfirmTwoFactor)
+	router.Delete("/totp", write, session, r.DisableTwoFactor)
+
+	router.Post("/reset", write, r.RequestReset)
+	router.Post("/reset/confirm", write, r.ConfirmReset)
+	router.Get("/mail", write, r.PollMail)
 }
 
 func (r authRouterImpl) Login(ctx *fiber.Ctx) error {
@@ -35,10 +88,18 @@ func (r authRouterImpl) Login(ctx *fiber.Ctx) error {
 		return err
 	}
 
+	if remaining := r.lockout.Check(request.GetUsername(), r.AddressOf(ctx, request.GetAddress())); remaining > 0 {
+		return r.RejectLocked(ctx, remaining)
+	}
+
 	// Search for authentication
 	var authentication AuthenticationImpl
 
 	if err := r.db.Where("username = ?", request.GetUsername()).First(&authentication).Error; err != nil {
+		if remaining := r.lockout.Fail(request.GetUsername(), r.AddressOf(ctx, request.GetAddress())); remaining > 0 {
+			return r.RejectLocked(ctx, remaining)
+		}
+
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Cannot find the authentication",
 		})
@@ -46,14 +107,92 @@ func (r authRouterImpl) Login(ctx *fiber.Ctx) error {
 
 	// Check if the password is correct
 	if !authentication.CheckPassword(request.GetPassword()) {
+		if remaining := r.lockout.Fail(request.GetUsername(), r.AddressOf(ctx, request.GetAddress())); remaining > 0 {
+			return r.RejectLocked(ctx, remaining)
+		}
+
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid password",
 		})
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Successfully logged in",
-	})
+	// Upgrade hashes from older algorithms or parameters while we know the password
+	if authentication.NeedsRehash() {
+		authentication.UpdatePassword(request.GetPassword())
+
+		if err := r.db.Model(&authentication).Update("password", authentication.GetPassword()).Error; err != nil {
+			log.Error().Err(err).Msg("Cannot rehash the password")
+		}
+	}
+
+	// Password alone is not enough, hand out a challenge for the second step
+	if authentication.HasTwoFactor() {
+		challenge, err := r.sessions.CreateChallenge(authentication.GetUniqueId())
+
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot create the second factor challenge")
+
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Cannot create the second factor challenge",
+			})
+		}
+
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":   "Second factor is required",
+			"challenge": challenge,
+		})
+	}
+
+	r.lockout.Succeed(request.GetUsername(), r.AddressOf(ctx, request.GetAddress()))
+
+	return r.StartSession(ctx, authentication, request.GetAddress())
+}
+
+func (r authRouterImpl) LoginTwoFactor(ctx *fiber.Ctx) error {
+	var body struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+		Address   string `json:"address"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	user := r.sessions.LoadChallenge(body.Challenge)
+
+	if len(user) == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired challenge",
+		})
+	}
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("unique_id = ?", user).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	if remaining := r.lockout.Check(authentication.GetUsername(), r.AddressOf(ctx, body.Address)); remaining > 0 {
+		return r.RejectLocked(ctx, remaining)
+	}
+
+	if !r.VerifySecondFactor(&authentication, body.Code) {
+		if remaining := r.lockout.Fail(authentication.GetUsername(), r.AddressOf(ctx, body.Address)); remaining > 0 {
+			return r.RejectLocked(ctx, remaining)
+		}
+
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid second factor code",
+		})
+	}
+
+	r.sessions.DeleteChallenge(body.Challenge)
+	r.lockout.Succeed(authentication.GetUsername(), r.AddressOf(ctx, body.Address))
+
+	return r.StartSession(ctx, authentication, body.Address)
 }
 
 func (r authRouterImpl) Register(ctx *fiber.Ctx) error {
@@ -63,6 +202,10 @@ func (r authRouterImpl) Register(ctx *fiber.Ctx) error {
 		return err
 	}
 
+	if violations := r.policy.Check(request.GetUsername(), request.GetPassword()); len(violations) > 0 {
+		return r.RejectPolicy(ctx, violations)
+	}
+
 	// Check if the username is already taken
 	var authentication AuthenticationImpl
 
@@ -72,9 +215,41 @@ func (r authRouterImpl) Register(ctx *fiber.Ctx) error {
 		})
 	}
 
+	// Authentications are bound one-to-one to the account with the same name
+	var account AccountImpl
+
+	if err := r.db.Where("username = ?", request.GetUsername()).First(&account).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find an account with this username",
+		})
+	}
+
+	if account.GetName() != request.GetUsername() {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Username must match the account name",
+		})
+	}
+
+	// Cracked accounts are only identified by their offline unique id
+	if account.GetAccountType() == data.CRACKED && account.GetUniqueId() != util.OfflinePlayerUUID(account.GetName()).String() {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Cracked account does not use its offline unique id",
+		})
+	}
+
+	if err := r.db.Where("unique_id = ?", account.GetUniqueId()).First(&authentication).Error; err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Account is already linked to an authentication",
+		})
+	}
+
 	// Create the authentication
 	authentication = AuthenticationImpl{
-		Username: request.GetUsername(),
+		UUIDData: data.UUIDData{
+			UUID: account.GetUniqueId(),
+		},
+
+		Username: account.GetName(),
 		Password: request.GetPassword(),
 
 		CreatedAt: time.Now(),
@@ -92,7 +267,8 @@ func (r authRouterImpl) Register(ctx *fiber.Ctx) error {
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Successfully registered",
+		"message":   "Successfully registered",
+		"unique_id": authentication.GetUniqueId(),
 	})
 }
 
@@ -101,16 +277,25 @@ func (r authRouterImpl) ChangePassword(ctx *fiber.Ctx) error {
 		Username    string `json:"username"`
 		Password    string `json:"password"`
 		NewPassword string `json:"new_password"`
+		Address     string `json:"address"`
 	}
 
 	if err := ctx.BodyParser(&body); err != nil {
 		return err
 	}
 
+	if remaining := r.lockout.Check(body.Username, r.AddressOf(ctx, body.Address)); remaining > 0 {
+		return r.RejectLocked(ctx, remaining)
+	}
+
 	// Search for authentication
 	var authentication AuthenticationImpl
 
 	if err := r.db.Where("username = ?", body.Username).First(&authentication).Error; err != nil {
+		if remaining := r.lockout.Fail(body.Username, r.AddressOf(ctx, body.Address)); remaining > 0 {
+			return r.RejectLocked(ctx, remaining)
+		}
+
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Cannot find the authentication",
 		})
@@ -118,13 +303,23 @@ func (r authRouterImpl) ChangePassword(ctx *fiber.Ctx) error {
 
 	// Check if the password is correct
 	if !authentication.CheckPassword(body.Password) {
+		if remaining := r.lockout.Fail(body.Username, r.AddressOf(ctx, body.Address)); remaining > 0 {
+			return r.RejectLocked(ctx, remaining)
+		}
+
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid password",
 		})
 	}
 
+	r.lockout.Succeed(body.Username, r.AddressOf(ctx, body.Address))
+
+	if violations := r.policy.CheckPassword(authentication.GetUsername(), body.NewPassword); len(violations) > 0 {
+		return r.RejectPolicy(ctx, violations)
+	}
+
 	// Update the password
-	authentication.UpdatePassword(body.Password)
+	authentication.UpdatePassword(body.NewPassword)
 
 	if err := r.db.Save(&authentication).Error; err != nil {
 		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
@@ -132,11 +327,682 @@ func (r authRouterImpl) ChangePassword(ctx *fiber.Ctx) error {
 		})
 	}
 
+	// Whoever knew the old password must not keep a session
+	if err := r.sessions.RevokeAll(authentication.GetUniqueId()); err != nil {
+		log.Error().Err(err).Msg("Cannot revoke sessions after password change")
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Successfully changed password",
 	})
 }
 
-func CreateAuthRouter(db *gorm.DB) AuthRouter {
-	return authRouterImpl{db: db}
+func (r authRouterImpl) Refresh(ctx *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	claims, err := r.issuer.Parse(body.RefreshToken, token.REFRESH)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	session := r.sessions.LoadSession(claims.Session)
+
+	if session == nil || session.GetUser() != claims.Subject {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Session has been revoked",
+		})
+	}
+
+	// Refresh tokens are single use, rotate them on every refresh.
+	revoked, err := r.issuer.Revoke(claims)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot revoke the refresh token")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot refresh the session",
+		})
+	}
+
+	// A concurrent refresh consumed the token first.
+	if !revoked {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	r.sessions.TouchSession(session, true)
+
+	pair, err := r.issuer.IssuePair(claims.Subject, claims.Username, claims.Session)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot issue session tokens")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot issue session tokens",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully refreshed session",
+
+		"unique_id":     claims.Subject,
+		"session_id":    claims.Session,
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+	})
+}
+
+func (r authRouterImpl) Logout(ctx *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	claims, err := r.issuer.Parse(body.RefreshToken, token.REFRESH)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	if err := r.sessions.RevokeSession(claims.Subject, claims.Session); err != nil {
+		log.Error().Err(err).Msg("Cannot revoke the session")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot finish the session",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully logged out",
+	})
+}
+
+func (r authRouterImpl) ListSessions(ctx *fiber.Ctx) error {
+	user, err := r.FilterUserByQuery(ctx)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"sessions": r.sessions.ListSessions(user),
+	})
+}
+
+func (r authRouterImpl) RevokeSession(ctx *fiber.Ctx) error {
+	user, err := r.FilterUserByQuery(ctx)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	id := ctx.Query("session")
+
+	if !util.EnsureUUID(id) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Session is not valid",
+		})
+	}
+
+	if err := r.sessions.RevokeSession(user, id); err != nil {
+		log.Error().Err(err).Msg("Cannot revoke the session")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot revoke the session",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully revoked session",
+	})
+}
+
+func (r authRouterImpl) RevokeSessions(ctx *fiber.Ctx) error {
+	user, err := r.FilterUserByQuery(ctx)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	if err := r.sessions.RevokeAll(user); err != nil {
+		log.Error().Err(err).Msg("Cannot revoke the sessions")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot revoke the sessions",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully revoked all sessions",
+	})
+}
+
+func (r authRouterImpl) ClearLockout(ctx *fiber.Ctx) error {
+	username, address := ctx.Query("user"), ctx.Query("address")
+
+	if len(username) == 0 && len(address) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "User or address is required",
+		})
+	}
+
+	if err := r.lockout.Clear(username, address); err != nil {
+		log.Error().Err(err).Msg("Cannot clear the lockout")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot clear the lockout",
+		})
+	}
+
+	log.Info().
+		Str("username", username).
+		Str("address", address).
+		Msg("Cleared lockout.")
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully cleared lockout",
+	})
+}
+
+func (r authRouterImpl) RejectPolicy(ctx *fiber.Ctx, violations []policy.Violation) error {
+	return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":      "Policy violation",
+		"violations": violations,
+	})
+}
+
+// Player address given on body, or the address of the caller when it gave none.
+func (r authRouterImpl) AddressOf(ctx *fiber.Ctx, address string) string {
+	if len(address) > 0 {
+		return address
+	}
+
+	return ctx.IP()
+}
+
+func (r authRouterImpl) RejectLocked(ctx *fiber.Ctx, remaining time.Duration) error {
+	seconds := int64(math.Ceil(remaining.Seconds()))
+
+	ctx.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+
+	return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "Too many failed attempts",
+		"retry_after": seconds,
+	})
+}
+
+func (r authRouterImpl) EnrollTwoFactor(ctx *fiber.Ctx) error {
+	claims, _ := SessionOf(ctx)
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("unique_id = ?", claims.Subject).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	if authentication.HasTwoFactor() {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Two factor authentication is already enabled",
+		})
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot generate the two factor secret")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot generate the two factor secret",
+		})
+	}
+
+	// Stays disabled until the user proves the authenticator was set up.
+	if err := r.db.Model(&authentication).Update("totp_secret", secret).Error; err != nil {
+		log.Error().Err(err).Msg("Cannot save the two factor secret")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot save the two factor secret",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Confirm the enrollment with a generated code",
+
+		"secret": secret,
+		"uri":    totp.URI(r.config.GetTwoFactorIssuer(), authentication.GetUsername(), secret),
+	})
+}
+
+func (r authRouterImpl) ConfirmTwoFactor(ctx *fiber.Ctx) error {
+	claims, _ := SessionOf(ctx)
+
+	var body struct {
+		Code string `json:"code"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("unique_id = ?", claims.Subject).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	if authentication.HasTwoFactor() {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Two factor authentication is already enabled",
+		})
+	}
+
+	if len(authentication.TOTPSecret) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two factor enrollment was not started",
+		})
+	}
+
+	step, ok := totp.Validate(authentication.TOTPSecret, body.Code, time.Now(), 1)
+
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid second factor code",
+		})
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(10)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot generate recovery codes")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot generate recovery codes",
+		})
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&authentication).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user = ?", authentication.GetUniqueId()).Delete(&RecoveryCodeImpl{}).Error; err != nil {
+			return err
+		}
+
+		recoveryCodes := make([]RecoveryCodeImpl, 0, len(codes))
+
+		for _, code := range codes {
+			recoveryCodes = append(recoveryCodes, RecoveryCodeImpl{
+				User: authentication.GetUniqueId(),
+				Hash: totp.HashRecoveryCode(code),
+
+				CreatedAt: time.Now(),
+			})
+		}
+
+		return tx.Create(&recoveryCodes).Error
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot enable two factor authentication")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot enable two factor authentication",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Successfully enabled two factor authentication",
+		"recovery_codes": codes,
+	})
+}
+
+func (r authRouterImpl) DisableTwoFactor(ctx *fiber.Ctx) error {
+	claims, _ := SessionOf(ctx)
+
+	var body struct {
+		Code string `json:"code"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("unique_id = ?", claims.Subject).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	if !authentication.HasTwoFactor() {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two factor authentication is not enabled",
+		})
+	}
+
+	if !r.VerifySecondFactor(&authentication, body.Code) {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid second factor code",
+		})
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&authentication).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user = ?", authentication.GetUniqueId()).Delete(&RecoveryCodeImpl{}).Error
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot disable two factor authentication")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot disable two factor authentication",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully disabled two factor authentication",
+	})
+}
+
+func (r authRouterImpl) RequestReset(ctx *fiber.Ctx) error {
+	var body struct {
+		Username string `json:"username"`
+		Address  string `json:"address"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	if remaining := r.lockout.Check(body.Username, r.AddressOf(ctx, body.Address)); remaining > 0 {
+		return r.RejectLocked(ctx, remaining)
+	}
+
+	// Same answer whether the username exists or not, so it cannot be used to enumerate them.
+	response := fiber.Map{
+		"message": "If the authentication exists, a reset token was sent",
+	}
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("username = ?", body.Username).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusAccepted).JSON(response)
+	}
+
+	token, err := r.resets.CreateToken(authentication.GetUniqueId())
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot create the reset token")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot create the reset token",
+		})
+	}
+
+	err = r.notifier.Notify(notifier.Notification{
+		User:     authentication.GetUniqueId(),
+		Username: authentication.GetUsername(),
+
+		Subject: "Password reset",
+		Body:    "Use the token " + token + " to reset your password, it expires in " + r.config.GetResetLifetime().String() + ".",
+
+		CreatedAt: time.Now(),
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot deliver the reset token")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot deliver the reset token",
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(response)
+}
+
+func (r authRouterImpl) ConfirmReset(ctx *fiber.Ctx) error {
+	var body struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return err
+	}
+
+	user := r.resets.LoadToken(body.Token)
+
+	if len(user) == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired reset token",
+		})
+	}
+
+	var authentication AuthenticationImpl
+
+	if err := r.db.Where("unique_id = ?", user).First(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	// Checked before redeeming, so a rejected password does not burn the token
+	if violations := r.policy.CheckPassword(authentication.GetUsername(), body.NewPassword); len(violations) > 0 {
+		return r.RejectPolicy(ctx, violations)
+	}
+
+	if r.resets.ConsumeToken(body.Token) != user {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired reset token",
+		})
+	}
+
+	authentication.UpdatePassword(body.NewPassword)
+
+	if err := r.db.Save(&authentication).Error; err != nil {
+		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Cannot update the authentication",
+		})
+	}
+
+	if err := r.sessions.RevokeAll(authentication.GetUniqueId()); err != nil {
+		log.Error().Err(err).Msg("Cannot revoke sessions after password reset")
+	}
+
+	if err := r.lockout.Clear(authentication.GetUsername(), ""); err != nil {
+		log.Error().Err(err).Msg("Cannot clear the lockout after password reset")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully reset password",
+	})
+}
+
+func (r authRouterImpl) PollMail(ctx *fiber.Ctx) error {
+	mailbox, ok := r.notifier.(notifier.Mailbox)
+
+	if !ok {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "In-game mail is disabled",
+		})
+	}
+
+	user, err := r.FilterUserByQuery(ctx)
+
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cannot find the authentication",
+		})
+	}
+
+	notifications, err := mailbox.Poll(user)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot poll in-game mail")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot poll in-game mail",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"mail": notifications,
+	})
+}
+
+// Accept either a fresh TOTP code or an unused recovery code, which is consumed.
+func (r authRouterImpl) VerifySecondFactor(authentication *AuthenticationImpl, code string) bool {
+	if step, ok := totp.Validate(authentication.TOTPSecret, code, time.Now(), 1); ok {
+		// Codes are single use, even inside their own window.
+		if step <= authentication.TOTPLastStep {
+			return false
+		}
+
+		result := r.db.Model(authentication).
+			Where("totp_last_step < ?", step).
+			Update("totp_last_step", step)
+
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	result := r.db.Where("user = ? AND hash = ?", authentication.GetUniqueId(), totp.HashRecoveryCode(code)).
+		Delete(&RecoveryCodeImpl{})
+
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Cannot consume the recovery code")
+		return false
+	}
+
+	return result.RowsAffected == 1
+}
+
+// Open a new session for the authentication and answer with its tokens.
+// Sessions record the player address given on body, the caller is usually a game server.
+func (r authRouterImpl) StartSession(ctx *fiber.Ctx, authentication AuthenticationImpl, address string) error {
+	session, err := r.sessions.CreateSession(
+		authentication.GetUniqueId(),
+		r.AddressOf(ctx, address),
+		ctx.Get(fiber.HeaderUserAgent),
+	)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot create the session")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot create the session",
+		})
+	}
+
+	pair, err := r.issuer.IssuePair(authentication.GetUniqueId(), authentication.GetUsername(), session.GetUniqueId())
+
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot issue session tokens")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cannot issue session tokens",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully logged in",
+
+		"unique_id":     authentication.GetUniqueId(),
+		"session_id":    session.GetUniqueId(),
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+
+		// Lobby servers load the profile from the same call
+		"account": r.RetrieveAccount(authentication.GetUniqueId()),
+	})
+}
+
+// Snapshot of the account linked to an authentication, from cache or database.
+func (r authRouterImpl) RetrieveAccount(unique string) data.Account {
+	if account := r.cache.LoadAccount(unique); account != nil {
+		return account
+	}
+
+	var account AccountImpl
+
+	if err := r.db.Preload(clause.Associations).Where("unique_id = ?", unique).First(&account).Error; err != nil {
+		return nil
+	}
+
+	r.cache.SaveAccount(account)
+
+	return account
+}
+
+// Resolve the authentication from the user query, accepting unique id or username.
+func (r authRouterImpl) FilterUserByQuery(ctx *fiber.Ctx) (string, error) {
+	user := ctx.Query("user")
+
+	var authentication AuthenticationImpl
+
+	query := r.db.Select("unique_id")
+
+	if util.EnsureUUID(user) {
+		query = query.Where("unique_id = ?", user)
+	} else {
+		query = query.Where("username = ?", user)
+	}
+
+	if err := query.First(&authentication).Error; err != nil {
+		return "", err
+	}
+
+	return authentication.GetUniqueId(), nil
+}
+
+func CreateAuthRouter(db *gorm.DB, cache repository.RedisRepository, config *config.Config, issuer token.Issuer, sessions repository.SessionRepository, lockout repository.LockoutRepository, resets repository.ResetRepository, notifier notifier.Notifier, policy policy.Policy) AuthRouter {
+	return authRouterImpl{
+		db:       db,
+		cache:    cache,
+		config:   config,
+		issuer:   issuer,
+		sessions: sessions,
+		lockout:  lockout,
+		resets:   resets,
+		notifier: notifier,
+		policy:   policy,
+	}
 }
@@ -0,0 +1,199 @@
+package router
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type CouponRouter interface {
+	WebRouter
+
+	MintCoupons(ctx *fiber.Ctx) error
+	ListCoupons(ctx *fiber.Ctx) error
+}
+
+type couponRouterImpl struct {
+	config     *config.Config
+	coupons    repository.CouponRepository
+	currencies repository.CurrencyRepository
+}
+
+func (r *couponRouterImpl) TakeEndpoints(router fiber.Router) {
+	router.Put("/", RequireScope(data.COUPON_ADMIN), r.MintCoupons)
+	router.Get("/", RequireScope(data.COUPON_ADMIN), r.ListCoupons)
+}
+
+func (r *couponRouterImpl) MintCoupons(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	batch, _ := body["batch"].(string)
+	code, _ := body["code"].(string)
+
+	if len(batch) > 64 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Batch must have at most 64 characters.",
+		})
+	}
+
+	count, err := util.ParseInt64(body["count"], 1)
+
+	if err != nil || count < 1 || count > 1000 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Count must be between 1 and 1000.",
+		})
+	}
+
+	if len(code) > 0 && (count != 1 || len(repository.NormalizeCode(code)) > 32) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Custom codes must have at most 32 characters and are minted one at a time.",
+		})
+	}
+
+	cash, err := util.ParseInt64(body["cash"], 0)
+
+	if err != nil || cash < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Cash must be a positive number.",
+		})
+	}
+
+	currency := r.config.GetDefaultCurrency()
+
+	if target, ok := body["currency"].(string); ok && len(target) > 0 {
+		if !r.currencies.HasCurrency(target) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Currency is not valid.",
+			})
+		}
+
+		currency = target
+	}
+
+	var group data.GroupType
+	var duration int64
+
+	if target, ok := body["group"].(string); ok && len(target) > 0 {
+		group, err = util.ParseGroupType(target)
+
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid group type: '" + target + "'.",
+			})
+		}
+
+		duration, err = util.ParseInt64(body["duration"], 0)
+
+		if err != nil || duration <= 0 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Duration in seconds is required along with a group.",
+			})
+		}
+	}
+
+	if cash == 0 && len(group) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Coupon must grant cash or a group.",
+		})
+	}
+
+	maxRedemptions, err := util.ParseInt64(body["max_redemptions"], 1)
+
+	if err != nil || maxRedemptions < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Max redemptions must be zero, for unlimited, or positive.",
+		})
+	}
+
+	perAccount, err := util.ParseInt64(body["per_account"], 1)
+
+	if err != nil || perAccount < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Per account must be zero, for unlimited, or positive.",
+		})
+	}
+
+	var expireAt *time.Time
+
+	if body["expire_at"] != nil {
+		unix, err := util.ParseInt64(body["expire_at"], 0)
+
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Expire at is not valid.",
+			})
+		}
+
+		target := time.Unix(unix, 0)
+		expireAt = &target
+	}
+
+	author := ""
+
+	if key, ok := APIKeyOf(ctx); ok {
+		author = key.GetName()
+	}
+
+	template := CreateCoupon("", "", batch, currency, cash, group, time.Duration(duration)*time.Second, maxRedemptions, perAccount, author, expireAt)
+
+	codes, err := r.coupons.MintCoupons(template, code, int(count))
+
+	if errors.Is(err, repository.ErrCouponExists) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Coupon already exists.",
+		})
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not mint coupons.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not mint coupons.",
+		})
+	}
+
+	util.DebugOutput("Minted %d coupons on batch '%s'", len(codes), batch)
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Coupons minted.",
+
+		"batch": batch,
+		"codes": codes,
+	})
+}
+
+func (r *couponRouterImpl) ListCoupons(ctx *fiber.Ctx) error {
+	coupons, err := r.coupons.ListCoupons(ctx.Query("batch"))
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not list coupons.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not list coupons.",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(coupons)
+}
+
+func CreateCouponRouter(config *config.Config, coupons repository.CouponRepository, currencies repository.CurrencyRepository) CouponRouter {
+	return &couponRouterImpl{
+		config:     config,
+		coupons:    coupons,
+		currencies: currencies,
+	}
+}
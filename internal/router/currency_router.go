@@ -0,0 +1,94 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/data"
+	"github.com/rs/zerolog/log"
+)
+
+type CurrencyRouter interface {
+	WebRouter
+
+	ListCurrencies(ctx *fiber.Ctx) error
+	CreateCurrency(ctx *fiber.Ctx) error
+}
+
+type currencyRouterImpl struct {
+	currencies repository.CurrencyRepository
+}
+
+func (r *currencyRouterImpl) TakeEndpoints(router fiber.Router) {
+	router.Get("/", RequireScope(data.ACCOUNT_READ), r.ListCurrencies)
+	router.Put("/", RequireScope(data.CURRENCY_WRITE), r.CreateCurrency)
+}
+
+func (r *currencyRouterImpl) ListCurrencies(ctx *fiber.Ctx) error {
+	currencies, err := r.currencies.ListCurrencies()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not list currencies.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not list currencies.",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(currencies)
+}
+
+func (r *currencyRouterImpl) CreateCurrency(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	name, ok := body["name"].(string)
+
+	if !ok || len(name) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Name is required.",
+		})
+	}
+
+	if r.currencies.HasCurrency(name) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Currency already exists.",
+		})
+	}
+
+	currency, err := r.currencies.CreateCurrency(name)
+
+	if errors.Is(err, repository.ErrInvalidCurrency) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Currency must be 1 to 32 lowercase letters, digits or underscores.",
+		})
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not create currency.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not create currency.",
+		})
+	}
+
+	util.DebugOutput("Currency %s created", currency.GetName())
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Currency created.",
+
+		"name": currency.GetName(),
+	})
+}
+
+func CreateCurrencyRouter(currencies repository.CurrencyRepository) CurrencyRouter {
+	return &currencyRouterImpl{
+		currencies: currencies,
+	}
+}
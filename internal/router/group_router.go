@@ -0,0 +1,393 @@
+package router
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type GroupRouter interface {
+	WebRouter
+
+	ListGroups(ctx *fiber.Ctx) error
+	FindGroup(ctx *fiber.Ctx) error
+	CreateGroup(ctx *fiber.Ctx) error
+	UpdateGroup(ctx *fiber.Ctx) error
+	DeleteGroup(ctx *fiber.Ctx) error
+	ListPermissions(ctx *fiber.Ctx) error
+	AddPermissions(ctx *fiber.Ctx) error
+	RemovePermissions(ctx *fiber.Ctx) error
+	AddParents(ctx *fiber.Ctx) error
+	RemoveParents(ctx *fiber.Ctx) error
+}
+
+type groupRouterImpl struct {
+	groups      repository.GroupRepository
+	permissions repository.PermissionRepository
+}
+
+func (r *groupRouterImpl) TakeEndpoints(router fiber.Router) {
+	router.Get("/", RequireScope(data.ACCOUNT_READ), r.ListGroups)
+	router.Get("/:name", RequireScope(data.ACCOUNT_READ), r.FindGroup)
+	router.Put("/", RequireScope(data.GROUP_ADMIN), r.CreateGroup)
+	router.Patch("/:name", RequireScope(data.GROUP_ADMIN), r.UpdateGroup)
+	router.Delete("/:name", RequireScope(data.GROUP_ADMIN), r.DeleteGroup)
+	router.Get("/:name/permissions", RequireScope(data.ACCOUNT_READ), r.ListPermissions)
+	router.Post("/:name/permissions", RequireScope(data.GROUP_ADMIN), r.AddPermissions)
+	router.Delete("/:name/permissions", RequireScope(data.GROUP_ADMIN), r.RemovePermissions)
+	router.Post("/:name/parents", RequireScope(data.GROUP_ADMIN), r.AddParents)
+	router.Delete("/:name/parents", RequireScope(data.GROUP_ADMIN), r.RemoveParents)
+}
+
+func (r *groupRouterImpl) ListGroups(ctx *fiber.Ctx) error {
+	groups, err := r.groups.ListGroups()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not list groups.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not list groups.",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(groups)
+}
+
+func (r *groupRouterImpl) FindGroup(ctx *fiber.Ctx) error {
+	group, err := r.groups.FindGroup(ctx.Params("name"))
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(group)
+}
+
+func (r *groupRouterImpl) CreateGroup(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	name, ok := body["name"].(string)
+
+	if !ok || len(name) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Name is required.",
+		})
+	}
+
+	group, message := r.GroupOf(body, CreateGroup(data.GroupType(name), "", "", 0, false).(GroupImpl))
+
+	if len(message) > 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": message,
+		})
+	}
+
+	created, err := r.groups.CreateGroup(group)
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	util.DebugOutput("Group %s created", created.GetName())
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Group created.",
+
+		"group": created,
+	})
+}
+
+func (r *groupRouterImpl) UpdateGroup(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	current, err := r.groups.FindGroup(ctx.Params("name"))
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	group, message := r.GroupOf(body, current.(GroupImpl))
+
+	if len(message) > 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": message,
+		})
+	}
+
+	updated, err := r.groups.UpdateGroup(group)
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	util.DebugOutput("Group %s updated", updated.GetName())
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Group updated.",
+
+		"group": updated,
+	})
+}
+
+func (r *groupRouterImpl) DeleteGroup(ctx *fiber.Ctx) error {
+	if err := r.groups.DeleteGroup(ctx.Params("name")); err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	util.DebugOutput("Group %s deleted", ctx.Params("name"))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Group deleted.",
+	})
+}
+
+func (r *groupRouterImpl) ListPermissions(ctx *fiber.Ctx) error {
+	group, err := r.groups.FindGroup(ctx.Params("name"))
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	nodes, err := r.permissions.ListNodes(group.GetName())
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	parents, err := r.permissions.ListParents(group.GetName())
+
+	if err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"group":   group.GetName(),
+		"nodes":   nodes,
+		"parents": parents,
+	})
+}
+
+func (r *groupRouterImpl) AddPermissions(ctx *fiber.Ctx) error {
+	group, nodes, err := r.NamesOf(ctx, "nodes")
+
+	if err != nil || len(group) == 0 {
+		return err
+	}
+
+	if err := r.permissions.AddNodes(group, nodes); err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	util.DebugOutput("Permissions added to group %s", group)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Permissions added.",
+	})
+}
+
+func (r *groupRouterImpl) RemovePermissions(ctx *fiber.Ctx) error {
+	group, nodes, err := r.NamesOf(ctx, "nodes")
+
+	if err != nil || len(group) == 0 {
+		return err
+	}
+
+	if err := r.permissions.RemoveNodes(group, nodes); err != nil {
+		return r.RejectGroup(ctx, err)
+	}
+
+	util.DebugOutput("Permissions removed from group %s", group)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Permissions removed.",
+	})
+}
+
+func (r *groupRouterImpl) AddParents(ctx *fiber.Ctx) error {
+	group, parents, err := r.NamesOf(ctx, "parents")
+
+	if err != nil || len(group) == 0 {
+		return err
+	}
+
+	for _, name := range parents {
+		parent, err := util.ParseGroupType(name)
+
+		if err != nil {
+			return r.RejectGroup(ctx, repository.ErrGroupNotFound)
+		}
+
+		if err := r.permissions.AddParent(group, parent); err != nil {
+			return r.RejectGroup(ctx, err)
+		}
+	}
+
+	util.DebugOutput("Parents added to group %s", group)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Parents added.",
+	})
+}
+
+func (r *groupRouterImpl) RemoveParents(ctx *fiber.Ctx) error {
+	group, parents, err := r.NamesOf(ctx, "parents")
+
+	if err != nil || len(group) == 0 {
+		return err
+	}
+
+	for _, name := range parents {
+		if err := r.permissions.RemoveParent(group, data.GroupType(strings.ToUpper(name))); err != nil {
+			return r.RejectGroup(ctx, err)
+		}
+	}
+
+	util.DebugOutput("Parents removed from group %s", group)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Parents removed.",
+	})
+}
+
+// Resolve the group on path along with the names listed on the field of body.
+// The response is already written when the group is empty.
+func (r *groupRouterImpl) NamesOf(ctx *fiber.Ctx, field string) (data.GroupType, []string, error) {
+	group, err := r.groups.FindGroup(ctx.Params("name"))
+
+	if err != nil {
+		return "", nil, r.RejectGroup(ctx, err)
+	}
+
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return "", nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	values, ok := body[field].([]interface{})
+
+	if !ok || len(values) == 0 {
+		return "", nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "A list of " + field + " is required.",
+		})
+	}
+
+	names := make([]string, 0, len(values))
+
+	for _, value := range values {
+		name, ok := value.(string)
+
+		if !ok || len(name) == 0 {
+			return "", nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "The " + field + " must be non-empty strings.",
+			})
+		}
+
+		names = append(names, name)
+	}
+
+	return group.GetName(), names, nil
+}
+
+// Apply the fields given on body to the group, returning a message when any is not valid.
+func (r *groupRouterImpl) GroupOf(body map[string]interface{}, group GroupImpl) (GroupImpl, string) {
+	if value, ok := body["prefix"]; ok {
+		prefix, ok := value.(string)
+
+		if !ok || len(prefix) > 32 {
+			return group, "Prefix must have at most 32 characters."
+		}
+
+		group.Prefix = prefix
+	}
+
+	if value, ok := body["color"]; ok {
+		color, ok := value.(string)
+
+		if !ok || len(color) > 16 {
+			return group, "Color must have at most 16 characters."
+		}
+
+		group.Color = color
+	}
+
+	if value, ok := body["weight"]; ok {
+		weight, err := util.ParseInt64(value, 0)
+
+		if err != nil || weight < math.MinInt32 || weight > math.MaxInt32 {
+			return group, "Weight isn't a number."
+		}
+
+		group.Weight = int(weight)
+	}
+
+	if value, ok := body["default"]; ok {
+		isDefault, ok := value.(bool)
+
+		if !ok {
+			return group, "Default must be a boolean."
+		}
+
+		group.Default = isDefault
+	}
+
+	return group, ""
+}
+
+func (r *groupRouterImpl) RejectGroup(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrGroupNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Group not found.",
+		})
+	case errors.Is(err, repository.ErrInvalidGroup):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Group name must be 1 to 18 letters, digits or underscores.",
+		})
+	case errors.Is(err, repository.ErrGroupExists):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Group already exists.",
+		})
+	case errors.Is(err, repository.ErrGroupInUse):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Group is still granted to accounts.",
+		})
+	case errors.Is(err, repository.ErrInvalidNode):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Permission nodes must be dot separated lowercase segments, optionally negated or ending on a wildcard.",
+		})
+	case errors.Is(err, repository.ErrInheritanceCycle):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Group would inherit from itself.",
+		})
+	}
+
+	log.Error().Err(err).Msg("Could not manage groups.")
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"message": "Could not manage groups.",
+	})
+}
+
+func CreateGroupRouter(groups repository.GroupRepository, permissions repository.PermissionRepository) GroupRouter {
+	return &groupRouterImpl{
+		groups:      groups,
+		permissions: permissions,
+	}
+}
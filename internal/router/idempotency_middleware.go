@@ -0,0 +1,112 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/rs/zerolog/log"
+)
+
+// Header identifying retries of the same request, also stored along ledger entries.
+const IdempotencyHeader = "Idempotency-Key"
+
+// Header set on responses replayed from a previous request.
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// Middleware running a request once per Idempotency-Key, retries receive the first response.
+func Idempotent(responses repository.IdempotencyRepository) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		idempotencyKey := ctx.Get(IdempotencyHeader)
+
+		if len(idempotencyKey) == 0 {
+			return ctx.Next()
+		}
+
+		if len(idempotencyKey) > 64 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Idempotency key must have at most 64 characters",
+			})
+		}
+
+		// Keys are chosen by clients, so they only collide within the same API key.
+		owner := "anonymous"
+
+		if key, ok := APIKeyOf(ctx); ok {
+			owner = key.GetName()
+		}
+
+		key := owner + "-" + idempotencyKey
+		fingerprint := Fingerprint(ctx)
+
+		stored, reserved, err := responses.Reserve(key, fingerprint)
+
+		// Fail closed, running a payment twice is worse than refusing it.
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot reserve idempotency key: " + key)
+
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Cannot process idempotent requests right now",
+			})
+		}
+
+		if !reserved {
+			if stored.Fingerprint != fingerprint {
+				return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error": "Idempotency key was already used for another request",
+				})
+			}
+
+			if stored.Status == 0 {
+				return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "A request with this idempotency key is still in progress",
+				})
+			}
+
+			util.DebugOutput("Replaying response for idempotency key %s", key)
+
+			ctx.Set(IdempotentReplayHeader, "true")
+			ctx.Set(fiber.HeaderContentType, stored.ContentType)
+
+			return ctx.Status(stored.Status).Send(stored.Body)
+		}
+
+		// Errors and server failures are not final, the client must be able to retry them.
+		if err := ctx.Next(); err != nil || ctx.Response().StatusCode() >= fiber.StatusInternalServerError {
+			if releaseErr := responses.Release(key); releaseErr != nil {
+				log.Error().Err(releaseErr).Msg("Cannot release idempotency key: " + key)
+			}
+
+			return err
+		}
+
+		err = responses.Complete(key, repository.StoredResponse{
+			Fingerprint: fingerprint,
+
+			Status:      ctx.Response().StatusCode(),
+			ContentType: string(ctx.Response().Header.ContentType()),
+			Body:        append([]byte(nil), ctx.Response().Body()...),
+		})
+
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot store response for idempotency key: " + key)
+		}
+
+		return nil
+	}
+}
+
+// Hash of what makes two requests the same, the method, path with query and body.
+func Fingerprint(ctx *fiber.Ctx) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(ctx.Method()))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(ctx.OriginalURL()))
+	hash.Write([]byte{'\n'})
+	hash.Write(ctx.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/token"
+	"github.com/luiz-otavio/galax/internal/util"
+)
+
+// Header carrying the access token, the Authorization one is taken by the API key.
+const SessionHeader = "X-Session-Token"
+
+const sessionLocal = "galax-session"
+
+// Middleware for routers which requires a valid session besides the API key.
+func RequireSession(issuer token.Issuer, sessions repository.SessionRepository) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		raw := ctx.Get(SessionHeader)
+
+		if len(raw) == 0 {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session token is required",
+			})
+		}
+
+		claims, err := issuer.Parse(raw, token.ACCESS)
+
+		if err != nil {
+			util.DebugOutput("Rejected session token from %s: %s", ctx.IP(), err.Error())
+
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid session token",
+			})
+		}
+
+		session := sessions.LoadSession(claims.Session)
+
+		if session == nil || session.GetUser() != claims.Subject {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session has been revoked",
+			})
+		}
+
+		sessions.TouchSession(session, false)
+
+		ctx.Locals(sessionLocal, claims)
+
+		return ctx.Next()
+	}
+}
+
+// Retrieve the session claims stored by RequireSession.
+func SessionOf(ctx *fiber.Ctx) (token.Claims, bool) {
+	claims, ok := ctx.Locals(sessionLocal).(token.Claims)
+
+	return claims, ok
+}
@@ -0,0 +1,214 @@
+package router
+
+import (
+	"errors"
+	"time"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/signature"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type StoreRouter interface {
+	WebRouter
+
+	Webhook(ctx *fiber.Ctx) error
+}
+
+type storeRouterImpl struct {
+	config     *config.Config
+	cache      repository.RedisRepository
+	store      repository.StoreRepository
+	currencies repository.CurrencyRepository
+}
+
+// The web store cannot hold an API key, so its routes are authenticated by their signature instead.
+func (r *storeRouterImpl) TakeEndpoints(router fiber.Router) {
+	router.Post("/webhook", r.Webhook)
+}
+
+func (r *storeRouterImpl) Webhook(ctx *fiber.Ctx) error {
+	secret := r.config.GetStoreSecret()
+
+	if len(secret) == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Store webhook is disabled.",
+		})
+	}
+
+	if !signature.Verify(secret, string(ctx.Body()), ctx.Get(r.config.GetStoreHeader())) {
+		util.DebugOutput("Rejected store webhook from %s", ctx.IP())
+
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid signature",
+		})
+	}
+
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	event, _ := body["type"].(string)
+	transaction, _ := body["transaction"].(string)
+
+	if len(transaction) == 0 || len(transaction) > 64 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Transaction must have between 1 and 64 characters.",
+		})
+	}
+
+	util.DebugOutput("Income store %s event for transaction %s.", event, transaction)
+
+	entry := repository.LedgerEntry{
+		Source: repository.StoreReason,
+	}
+
+	var outcome repository.StoreOutcome
+	var err error
+
+	switch event {
+	case "purchase", "renewal":
+		player, _ := body["player"].(string)
+		pkg, _ := body["package"].(string)
+
+		if !util.EnsureUUID(player) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Player is not valid.",
+			})
+		}
+
+		reward, ok, rewardErr := r.RewardOf(pkg)
+
+		if !ok {
+			return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Unknown package: '" + pkg + "'.",
+			})
+		}
+
+		// Answered with an error so the store retries once the package is fixed.
+		if rewardErr != nil {
+			log.Error().Err(rewardErr).Msg("Store package '" + pkg + "' is misconfigured.")
+
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Package is misconfigured.",
+			})
+		}
+
+		outcome, err = r.store.Fulfil(transaction, player, pkg, reward, entry)
+	case "chargeback", "refund":
+		outcome, err = r.store.Revoke(transaction, event, entry)
+	default:
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Event ignored.",
+		})
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrPurchaseProcessed):
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Transaction already processed.",
+		})
+	case errors.Is(err, repository.ErrPurchaseNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Purchase not found.",
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Account not found.",
+		})
+	case errors.Is(err, repository.ErrOutOfBounds):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Balance would leave the limits of the currency.",
+		})
+	case err != nil:
+		log.Error().Err(err).Msg("Could not process store event for transaction " + transaction + ".")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not process store event.",
+		})
+	}
+
+	user := outcome.Purchase.GetUser()
+
+	if outcome.Transaction != nil {
+		r.cache.UpdateWallet(user, outcome.Transaction.GetCurrency(), outcome.Transaction.GetBalance())
+	}
+
+	// Accounts which are not cached are loaded from database later on, already up to date.
+	if account := r.cache.LoadAccount(user); account != nil && outcome.Group != nil {
+		if outcome.Removed {
+			r.cache.RemoveGroup(account, *outcome.Group)
+		} else {
+			r.cache.AddGroup(account, *outcome.Group)
+		}
+	}
+
+	// Permissions are cached apart from the account, so they are dropped even when it is not cached.
+	if outcome.Group != nil {
+		r.cache.InvalidatePermissions(user)
+	}
+
+	util.DebugOutput("Processed store %s event for transaction %s of user %s.", event, transaction, user)
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Transaction processed.",
+		"purchase": outcome.Purchase,
+	})
+}
+
+// Resolve the reward of a package from config, whether it exists and whether it is valid.
+func (r *storeRouterImpl) RewardOf(pkg string) (repository.StoreReward, bool, error) {
+	target, ok := r.config.GetStorePackage(pkg)
+
+	if !ok {
+		return repository.StoreReward{}, false, nil
+	}
+
+	reward := repository.StoreReward{
+		Currency: target.Currency,
+		Cash:     target.Cash,
+
+		Duration: time.Duration(target.Duration) * time.Second,
+	}
+
+	if len(reward.Currency) == 0 {
+		reward.Currency = r.config.GetDefaultCurrency()
+	}
+
+	if reward.Cash < 0 || (reward.Cash > 0 && !r.currencies.HasCurrency(reward.Currency)) {
+		return reward, true, errors.New("cash must be positive and on an existing currency")
+	}
+
+	if len(target.Group) > 0 {
+		group, err := util.ParseGroupType(target.Group)
+
+		if err != nil {
+			return reward, true, err
+		}
+
+		if reward.Duration <= 0 {
+			return reward, true, errors.New("duration is required along with a group")
+		}
+
+		reward.Group = group
+	}
+
+	return reward, true, nil
+}
+
+func CreateStoreRouter(config *config.Config, cache repository.RedisRepository, store repository.StoreRepository, currencies repository.CurrencyRepository) StoreRouter {
+	return &storeRouterImpl{
+		config:     config,
+		cache:      cache,
+		store:      store,
+		currencies: currencies,
+	}
+}
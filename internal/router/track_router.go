@@ -0,0 +1,219 @@
+package router
+
+import (
+	"errors"
+
+	. "github.com/luiz-otavio/galax/internal/impl"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/internal/util"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type TrackRouter interface {
+	WebRouter
+
+	ListTracks(ctx *fiber.Ctx) error
+	FindTrack(ctx *fiber.Ctx) error
+	CreateTrack(ctx *fiber.Ctx) error
+	UpdateTrack(ctx *fiber.Ctx) error
+	DeleteTrack(ctx *fiber.Ctx) error
+}
+
+type trackRouterImpl struct {
+	tracks repository.TrackRepository
+}
+
+func (r *trackRouterImpl) TakeEndpoints(router fiber.Router) {
+	router.Get("/", RequireScope(data.ACCOUNT_READ), r.ListTracks)
+	router.Get("/:name", RequireScope(data.ACCOUNT_READ), r.FindTrack)
+	router.Put("/", RequireScope(data.GROUP_ADMIN), r.CreateTrack)
+	router.Patch("/:name", RequireScope(data.GROUP_ADMIN), r.UpdateTrack)
+	router.Delete("/:name", RequireScope(data.GROUP_ADMIN), r.DeleteTrack)
+}
+
+func (r *trackRouterImpl) ListTracks(ctx *fiber.Ctx) error {
+	tracks, err := r.tracks.ListTracks()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not list tracks.")
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not list tracks.",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(tracks)
+}
+
+func (r *trackRouterImpl) FindTrack(ctx *fiber.Ctx) error {
+	track, err := r.tracks.FindTrack(ctx.Params("name"))
+
+	if err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(track)
+}
+
+func (r *trackRouterImpl) CreateTrack(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	name, ok := body["name"].(string)
+
+	if !ok || len(name) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Name is required.",
+		})
+	}
+
+	ladder, ok := r.LadderOf(body["groups"])
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Groups must be a list of group names, from the lowest rank.",
+		})
+	}
+
+	created, err := r.tracks.CreateTrack(CreateTrack(name, ladder))
+
+	if err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	util.DebugOutput("Track %s created", created.GetName())
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Track created.",
+
+		"track": created,
+	})
+}
+
+func (r *trackRouterImpl) UpdateTrack(ctx *fiber.Ctx) error {
+	var body map[string]interface{}
+
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Could not parse body.",
+		})
+	}
+
+	current, err := r.tracks.FindTrack(ctx.Params("name"))
+
+	if err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	ladder, ok := r.LadderOf(body["groups"])
+
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Groups must be a list of group names, from the lowest rank.",
+		})
+	}
+
+	track := current.(TrackImpl)
+	track.Groups = ladder
+
+	updated, err := r.tracks.UpdateTrack(track)
+
+	if err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	util.DebugOutput("Track %s updated", updated.GetName())
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Track updated.",
+
+		"track": updated,
+	})
+}
+
+func (r *trackRouterImpl) DeleteTrack(ctx *fiber.Ctx) error {
+	if err := r.tracks.DeleteTrack(ctx.Params("name")); err != nil {
+		return RejectTrack(ctx, err)
+	}
+
+	util.DebugOutput("Track %s deleted", ctx.Params("name"))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Track deleted.",
+	})
+}
+
+// Group names listed on body, validated against the registry by the repository.
+func (r *trackRouterImpl) LadderOf(value interface{}) (data.GroupLadder, bool) {
+	groups, ok := value.([]interface{})
+
+	if !ok {
+		return nil, false
+	}
+
+	ladder := data.GroupLadder{}
+
+	for _, group := range groups {
+		name, ok := group.(string)
+
+		if !ok {
+			return nil, false
+		}
+
+		ladder = append(ladder, data.GroupType(name))
+	}
+
+	return ladder, true
+}
+
+// Shared with promotions, which fail on the same tracks.
+func RejectTrack(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrTrackNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Track not found.",
+		})
+	case errors.Is(err, repository.ErrInvalidTrack):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Track name must be 1 to 32 lowercase letters, digits or underscores.",
+		})
+	case errors.Is(err, repository.ErrInvalidLadder):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Track must list distinct groups from the registry.",
+		})
+	case errors.Is(err, repository.ErrTrackExists):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Track already exists.",
+		})
+	case errors.Is(err, repository.ErrTrackEnd):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Account is already on the last group of the track.",
+		})
+	case errors.Is(err, repository.ErrNotOnTrack):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Account holds no group of the track.",
+		})
+	case errors.Is(err, repository.ErrOutranked):
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Author cannot act on groups above their own.",
+		})
+	}
+
+	log.Error().Err(err).Msg("Could not manage tracks.")
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"message": "Could not manage tracks.",
+	})
+}
+
+func CreateTrackRouter(tracks repository.TrackRepository) TrackRouter {
+	return &trackRouterImpl{
+		tracks: tracks,
+	}
+}
@@ -0,0 +1,251 @@
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/luiz-otavio/galax/internal/repository"
+	"github.com/luiz-otavio/galax/pkg/config"
+	"github.com/luiz-otavio/galax/pkg/data"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Published on the sweeper channel for every expired grant, so game servers can refresh the rank shown.
+type Expiry struct {
+	User    string            `json:"user"`
+	Group   data.GroupType    `json:"group"`
+	Context data.GroupContext `json:"context"`
+
+	ExpireAt time.Time `json:"expire_at"`
+
+	// Current group of the account once the grant is removed.
+	CurrentGroup data.GroupType `json:"current_group"`
+}
+
+type Sweeper interface {
+	// Remove every grant expired by now in batches, returning the amount removed.
+	Sweep() (int, error)
+
+	// Sweep on every interval in background until shutdown.
+	Initialize(interval time.Duration)
+	Shutdown()
+}
+
+type sweeperImpl struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	cache  repository.RedisRepository
+	groups repository.GroupRepository
+	config *config.Config
+
+	stop chan struct{}
+}
+
+func (sweeper *sweeperImpl) Sweep() (int, error) {
+	now := time.Now()
+	batch := sweeper.config.GetSweepBatch()
+
+	removed := 0
+
+	for {
+		var expired []data.GroupInfo
+
+		err := sweeper.db.
+			Where("expire_at <= ?", now).
+			Order("expire_at ASC").
+			Limit(batch).
+			Find(&expired).Error
+
+		if err != nil {
+			return removed, err
+		}
+
+		users := []string{}
+		grants := map[string][]data.GroupInfo{}
+
+		for _, grant := range expired {
+			if _, ok := grants[grant.User]; !ok {
+				users = append(users, grant.User)
+			}
+
+			grants[grant.User] = append(grants[grant.User], grant)
+		}
+
+		for _, user := range users {
+			expiries, err := sweeper.expire(user, grants[user], now)
+
+			if err != nil {
+				return removed, err
+			}
+
+			removed += len(expiries)
+
+			sweeper.publish(expiries)
+		}
+
+		if len(expired) < batch {
+			return removed, nil
+		}
+	}
+}
+
+// Remove the expired grants of the user, recomputing the current group when it was one of them.
+func (sweeper *sweeperImpl) expire(user string, expired []data.GroupInfo, now time.Time) ([]Expiry, error) {
+	expiries := []Expiry{}
+
+	var current data.GroupType
+	changed := false
+
+	err := sweeper.db.Transaction(func(tx *gorm.DB) error {
+		removed := map[data.GroupType]bool{}
+
+		for _, grant := range expired {
+			// Grants extended since they were found no longer match and are kept.
+			result := tx.Where("user = ? AND role = ? AND context = ? AND expire_at <= ?", user, grant.Group, grant.Context.String(), now).Delete(&data.GroupInfo{})
+
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			removed[grant.Group] = true
+
+			expiries = append(expiries, Expiry{
+				User:    user,
+				Group:   grant.Group,
+				Context: grant.Context,
+
+				ExpireAt: grant.ExpireAt,
+			})
+		}
+
+		if len(expiries) == 0 {
+			return nil
+		}
+
+		var metadata data.MetadataSet
+
+		err := tx.Where("user = ?", user).First(&metadata).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		current = metadata.CurrentGroup
+
+		// A group chosen by the player is kept while it is still held.
+		if len(current) > 0 && !removed[current] {
+			return nil
+		}
+
+		var remaining []data.GroupInfo
+
+		if err := tx.Where("user = ?", user).Find(&remaining).Error; err != nil {
+			return err
+		}
+
+		// The current group is shown everywhere, so only grants without context count.
+		current, err = sweeper.groups.PrimaryGroup(remaining, data.GroupContext{})
+
+		if err != nil {
+			return err
+		}
+
+		changed = true
+
+		return tx.Model(&data.MetadataSet{}).Where("user = ?", user).Update("current_group", current).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range expiries {
+		expiries[i].CurrentGroup = current
+	}
+
+	// Accounts off cache are loaded from database later on, already without the grants.
+	if account := sweeper.cache.LoadAccount(user); account != nil && len(expiries) > 0 {
+		for _, expiry := range expiries {
+			sweeper.cache.RemoveGroup(account, data.GroupInfo{Group: expiry.Group, Context: expiry.Context})
+		}
+
+		if changed {
+			sweeper.cache.UpdateMetadata(user, "current_group", string(current))
+		}
+	}
+
+	return expiries, nil
+}
+
+func (sweeper *sweeperImpl) publish(expiries []Expiry) {
+	context := context.Background()
+	channel := sweeper.config.GetSweepChannel()
+
+	for _, expiry := range expiries {
+		message, err := json.Marshal(expiry)
+
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot encode group expiry of account: " + expiry.User)
+			continue
+		}
+
+		if err := sweeper.redis.Publish(context, channel, message).Err(); err != nil {
+			log.Error().Err(err).Msg("Cannot publish group expiry of account: " + expiry.User)
+		}
+	}
+}
+
+func (sweeper *sweeperImpl) Initialize(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sweeper.stop:
+				return
+			case <-ticker.C:
+			}
+
+			removed, err := sweeper.Sweep()
+
+			if err != nil {
+				log.Error().Err(err).Int("removed", removed).Msg("Cannot sweep expired groups.")
+				continue
+			}
+
+			if removed > 0 {
+				log.Info().Int("removed", removed).Msg("Swept expired groups.")
+			}
+		}
+	}()
+}
+
+func (sweeper *sweeperImpl) Shutdown() {
+	close(sweeper.stop)
+}
+
+func CreateSweeper(db *gorm.DB, redis *redis.Client, cache repository.RedisRepository, groups repository.GroupRepository, config *config.Config) Sweeper {
+	return &sweeperImpl{
+		db:     db,
+		redis:  redis,
+		cache:  cache,
+		groups: groups,
+		config: config,
+
+		stop: make(chan struct{}),
+	}
+}
@@ -0,0 +1,208 @@
+package token
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	ACCESS  Kind = "access"
+	REFRESH Kind = "refresh"
+)
+
+var (
+	ErrMalformed = errors.New("malformed token")
+	ErrSignature = errors.New("invalid token signature")
+	ErrExpired   = errors.New("token has expired")
+	ErrKind      = errors.New("unexpected token kind")
+	ErrRevoked   = errors.New("token has been revoked")
+)
+
+// Fixed JOSE header, tokens are plain HS256 JWTs so other services can verify them.
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type Claims struct {
+	ID       string `json:"jti"`
+	Session  string `json:"sid"`
+	Subject  string `json:"sub"`
+	Username string `json:"name"`
+	Kind     Kind   `json:"typ"`
+
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+func (claims Claims) GetExpiresAt() time.Time {
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type Issuer interface {
+	Issue(subject, username, session string, kind Kind) (string, Claims, error)
+	IssuePair(subject, username, session string) (Pair, error)
+
+	Parse(token string, kind Kind) (Claims, error)
+
+	// Deny the token until it expires, false when it was revoked or expired already.
+	// Only one of concurrent calls on the same token gets true, so it can consume single use tokens.
+	Revoke(claims Claims) (bool, error)
+	IsRevoked(id string) bool
+}
+
+type issuerImpl struct {
+	secret []byte
+
+	access  time.Duration
+	refresh time.Duration
+
+	redis *redis.Client
+	key   string
+}
+
+func (issuer issuerImpl) Issue(subject, username, session string, kind Kind) (string, Claims, error) {
+	lifetime := issuer.access
+
+	if kind == REFRESH {
+		lifetime = issuer.refresh
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		ID:       uuid.NewString(),
+		Session:  session,
+		Subject:  subject,
+		Username: username,
+		Kind:     kind,
+
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(lifetime).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", claims, err
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + issuer.sign(unsigned), claims, nil
+}
+
+func (issuer issuerImpl) IssuePair(subject, username, session string) (Pair, error) {
+	access, _, err := issuer.Issue(subject, username, session, ACCESS)
+
+	if err != nil {
+		return Pair{}, err
+	}
+
+	refresh, _, err := issuer.Issue(subject, username, session, REFRESH)
+
+	if err != nil {
+		return Pair{}, err
+	}
+
+	return Pair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(issuer.access.Seconds()),
+	}, nil
+}
+
+func (issuer issuerImpl) Parse(token string, kind Kind) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 || parts[0] != header {
+		return claims, ErrMalformed
+	}
+
+	expected := issuer.sign(parts[0] + "." + parts[1])
+
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return claims, ErrSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+
+	if err != nil {
+		return claims, ErrMalformed
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrMalformed
+	}
+
+	if claims.Kind != kind {
+		return claims, ErrKind
+	}
+
+	if time.Now().After(claims.GetExpiresAt()) {
+		return claims, ErrExpired
+	}
+
+	if issuer.IsRevoked(claims.ID) {
+		return claims, ErrRevoked
+	}
+
+	return claims, nil
+}
+
+func (issuer issuerImpl) Revoke(claims Claims) (bool, error) {
+	remaining := time.Until(claims.GetExpiresAt())
+
+	// Already expired, nothing to deny.
+	if remaining <= 0 {
+		return false, nil
+	}
+
+	return issuer.redis.SetNX(context.Background(), issuer.key+"-revoked-"+claims.ID, claims.Subject, remaining).Result()
+}
+
+func (issuer issuerImpl) IsRevoked(id string) bool {
+	exists, err := issuer.redis.Exists(context.Background(), issuer.key+"-revoked-"+id).Result()
+
+	// Fail closed, we cannot tell whether the token is still valid.
+	if err != nil {
+		return true
+	}
+
+	return exists > 0
+}
+
+func (issuer issuerImpl) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, issuer.secret)
+	mac.Write([]byte(unsigned))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func CreateIssuer(secret string, access, refresh time.Duration, client *redis.Client, key string) Issuer {
+	return issuerImpl{
+		secret: []byte(secret),
+
+		access:  access,
+		refresh: refresh,
+
+		redis: client,
+		key:   key,
+	}
+}
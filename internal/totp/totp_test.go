@@ -0,0 +1,121 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Base32 of the ASCII "12345678901234567890" secret from RFC 6238 appendix B.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// Last six digits of the SHA1 vectors, the RFC lists them with eight.
+	tests := []struct {
+		at   int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(test.at, 0)))
+
+		if err != nil {
+			t.Fatalf("Code(%d) returned %v", test.at, err)
+		}
+
+		if code != test.code {
+			t.Errorf("Code(%d) = %s, want %s", test.at, code, test.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	code, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(59, 0)))
+
+	if err != nil || code != "287082" {
+		t.Errorf("Code on lowercase secret = %s, %v, want 287082", code, err)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code on invalid secret returned no error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		skew int64
+		step int64
+		ok   bool
+	}{
+		{"current step", "050471", at, 0, Step(at), true},
+		{"surrounding spaces", " 050471 ", at, 0, Step(at), true},
+		{"previous step within skew", "050471", at.Add(Period * time.Second), 1, Step(at), true},
+		{"previous step without skew", "050471", at.Add(Period * time.Second), 0, 0, false},
+		{"step beyond skew", "050471", at.Add(2 * Period * time.Second), 1, 0, false},
+		{"wrong code", "000000", at, 1, 0, false},
+		{"short code", "05047", at, 1, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, test.code, test.at, test.skew)
+
+			if ok != test.ok || step != test.step {
+				t.Errorf("Validate = %d, %t, want %d, %t", step, ok, test.step, test.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("generated secret %s cannot be used: %v", secret, err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("recovery code %s is not formatted as xxxxx-xxxxx", code)
+		}
+
+		if seen[code] {
+			t.Errorf("recovery code %s generated twice", code)
+		}
+
+		seen[code] = true
+
+		// Typed back without the dash or in uppercase, it must hash the same.
+		if HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != HashRecoveryCode(code) {
+			t.Errorf("recovery code %s hashes differently once normalized", code)
+		}
+	}
+}
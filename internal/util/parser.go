@@ -3,6 +3,7 @@ package util
 import (
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -14,7 +15,10 @@ import (
 	"github.com/google/uuid"
 )
 
-func ParseInfo(uuid string, group string, source map[string]string) (data.GroupInfo, error) {
+// Parse the grant hash stored under the key of the groups set, see data.GroupInfo.Key.
+func ParseInfo(uuid string, key string, source map[string]string) (data.GroupInfo, error) {
+	group, _, _ := strings.Cut(key, "@")
+
 	createdAt, err := ParseUnix(source["createdAt"], -1)
 
 	if err != nil {
@@ -33,58 +37,48 @@ func ParseInfo(uuid string, group string, source map[string]string) (data.GroupI
 		return data.GroupInfo{}, errors.New("cannot parse author ar string to uuid")
 	}
 
-	return CreateGroupInfo(uuid, author.(string), data.GroupType(group), expireAt, createdAt), nil
-}
-
-func ParseMetadataSet(source map[string]string) (data.MetadataSet, error) {
-	metadata := data.MetadataSet{}
+	context, err := data.ParseGroupContext(source["context"])
 
-	if skin, err := EnsureType(source["skin"], reflect.String, "cannot parse skin type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.Skin = skin.(string)
+	if err != nil {
+		return data.GroupInfo{}, errors.New("cannot parse context of group info")
 	}
 
-	if name, err := EnsureType(source["name"], reflect.String, "cannot parse name type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.Name = name.(string)
-	}
+	info := CreateGroupInfo(uuid, author.(string), data.GroupType(group), expireAt, createdAt)
+	info.Context = context
 
-	if vanish, err := EnsureType(source["vanish"], reflect.Bool, "cannot parse vanish type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.Vanish = vanish.(bool)
-	}
+	return info, nil
+}
 
-	if flying, err := EnsureType(source["flying"], reflect.Bool, "cannot parse flying type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.Flying = flying.(bool)
-	}
+// Parse the metadata hash from redis, which holds every value as string and booleans as "1" or "0".
+func ParseMetadataSet(source map[string]string) (data.MetadataSet, error) {
+	metadata := data.MetadataSet{
+		Skin: source["skin"],
+		Name: source["name"],
 
-	if seeAllPlayers, err := EnsureType(source["see_all_players"], reflect.Bool, "cannot parse see all players type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.SeeAllPlayers = seeAllPlayers.(bool)
+		CurrentGroup: data.GroupType(source["current_group"]),
 	}
 
-	if publicTell, err := EnsureType(source["public_tell"], reflect.Bool, "cannot parse public tell type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.EnablePublicTell = publicTell.(bool)
+	flags := map[string]*bool{
+		"vanish":          &metadata.Vanish,
+		"flying":          &metadata.Flying,
+		"see_all_players": &metadata.SeeAllPlayers,
+		"public_tell":     &metadata.EnablePublicTell,
+		"staff_chat":      &metadata.SeeAllStaffChat,
+		"see_all_reports": &metadata.SeeAllReports,
 	}
 
-	if staffChat, err := EnsureType(source["staff_chat"], reflect.Bool, "cannot parse staff chat type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.SeeAllStaffChat = staffChat.(bool)
-	}
+	for key, target := range flags {
+		if len(source[key]) == 0 {
+			continue
+		}
 
-	if seeAllReports, err := EnsureType(source["see_all_reports"], reflect.Bool, "cannot parse see all reports type"); err != nil {
-		return metadata, err
-	} else {
-		metadata.SeeAllReports = seeAllReports.(bool)
+		value, err := strconv.ParseBool(source[key])
+
+		if err != nil {
+			return metadata, errors.New("cannot parse " + strings.ReplaceAll(key, "_", " ") + " type")
+		}
+
+		*target = value
 	}
 
 	return metadata, nil
@@ -143,7 +137,65 @@ func ParseUnix(value string, def int64) (time.Time, error) {
 	return time.Unix(target, 0), nil
 }
 
+// Parse a cash amount, numbers are rounded and strings keep the precision floats lose beyond 2^53.
+func ParseCash(value interface{}) (int64, error) {
+	switch target := value.(type) {
+	case float64:
+		rounded := math.Round(target)
+
+		// 2^63 is the first float64 out of range, both ends are exact powers of two.
+		if math.IsNaN(rounded) || rounded >= math.MaxInt64 || rounded < math.MinInt64 {
+			return 0, errors.New("cash is out of range")
+		}
+
+		return int64(rounded), nil
+	case string:
+		return strconv.ParseInt(target, 10, 64)
+	}
+
+	return 0, errors.New("cash isn't a number")
+}
+
+// Parse an optional number from a body, falling back to def when it is absent.
+func ParseInt64(value interface{}, def int64) (int64, error) {
+	if value == nil {
+		return def, nil
+	}
+
+	return ParseCash(value)
+}
+
+// Parse an integer query value, falling back to def when it is absent.
+func ParseInt(value string, def int) (int, error) {
+	if len(value) == 0 {
+		return def, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+// Registry groups are resolved against, the legacy groups are used until it is set on startup.
+type GroupRegistry interface {
+	FindGroup(name string) (data.Group, error)
+}
+
+var groups GroupRegistry
+
+func SetGroupRegistry(registry GroupRegistry) {
+	groups = registry
+}
+
 func ParseGroupType(group string) (data.GroupType, error) {
+	if groups != nil {
+		target, err := groups.FindGroup(group)
+
+		if err != nil {
+			return data.UNKNOWN, err
+		}
+
+		return target.GetName(), nil
+	}
+
 	switch strings.ToLower(group) {
 	case "owner":
 		return data.OWNER, nil
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luiz-otavio/galax/pkg/signature"
+)
+
+// Signs requests to Galax with HMAC-SHA256, for keys created with the --signing flag.
+type Signer struct {
+	key    string
+	secret string
+}
+
+func (signer Signer) Sign(request *http.Request) error {
+	var body []byte
+
+	if request.Body != nil {
+		read, err := io.ReadAll(request.Body)
+
+		if err != nil {
+			return err
+		}
+
+		request.Body.Close()
+
+		body = read
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	raw := make([]byte, 16)
+
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+
+	nonce := hex.EncodeToString(raw)
+	timestamp := time.Now().Unix()
+
+	canonical := signature.Canonical(request.Method, request.URL.RequestURI(), body, timestamp, nonce)
+
+	request.Header.Set(signature.HeaderKey, signer.key)
+	request.Header.Set(signature.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(signature.HeaderNonce, nonce)
+	request.Header.Set(signature.HeaderSignature, signature.Compute(signer.secret, canonical))
+
+	return nil
+}
+
+// RoundTripper signing every request before handing it to the next one.
+type SigningTransport struct {
+	Signer Signer
+	Next   http.RoundTripper
+}
+
+func (transport SigningTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	cloned := request.Clone(request.Context())
+
+	if request.Body != nil && request.GetBody != nil {
+		body, err := request.GetBody()
+
+		if err != nil {
+			return nil, err
+		}
+
+		cloned.Body = body
+	}
+
+	if err := transport.Signer.Sign(cloned); err != nil {
+		return nil, err
+	}
+
+	next := transport.Next
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return next.RoundTrip(cloned)
+}
+
+func CreateSigner(key, secret string) Signer {
+	return Signer{
+		key:    key,
+		secret: secret,
+	}
+}
@@ -1,39 +1,155 @@
 package config
 
 import (
+	"math"
 	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
 type Config struct {
-	logging struct {
+	Logging struct {
 		Debug bool
-	}
+	} `toml:"logging"`
 
-	api struct {
-		Key string
-	}
+	API struct {
+		Key      string
+		CacheKey string `toml:"cache_key"`
+		Cache    int64
+	} `toml:"api"`
 
-	mysql struct {
+	MySQL struct {
 		DSN string
-	}
+	} `toml:"mysql"`
 
-	redis struct {
+	Redis struct {
 		DSN      string
 		Interval int64
 		Key      string
-	}
+	} `toml:"redis"`
 
-	server struct {
+	Server struct {
 		Binding string
-	}
+	} `toml:"server"`
 
-	worker struct {
+	Worker struct {
 		Parallelism int
 		Interval    int
 		Iterations  int
-	}
+	} `toml:"worker"`
+
+	Session struct {
+		Key     string
+		Secret  string
+		Access  int64
+		Refresh int64
+	} `toml:"session"`
+
+	Lockout struct {
+		Key              string
+		Threshold        int64
+		AddressThreshold int64 `toml:"address_threshold"`
+		Window           int64
+		Base             int64
+		Max              int64
+	} `toml:"lockout"`
+
+	TwoFactor struct {
+		Issuer string
+	} `toml:"totp"`
+
+	Reset struct {
+		Key      string
+		Lifetime int64
+		Notifier string
+		File     string
+	} `toml:"reset"`
+
+	Mail struct {
+		Key      string
+		Lifetime int64
+	} `toml:"mail"`
+
+	Password struct {
+		Algorithm   string
+		Cost        int
+		Memory      uint32
+		Iterations  uint32
+		Parallelism uint8
+	} `toml:"password"`
+
+	Policy struct {
+		Username      string
+		MinPassword   int  `toml:"min_password"`
+		MaxPassword   int  `toml:"max_password"`
+		RequireLower  bool `toml:"require_lower"`
+		RequireUpper  bool `toml:"require_upper"`
+		RequireDigit  bool `toml:"require_digit"`
+		RequireSymbol bool `toml:"require_symbol"`
+		DenyCommon    bool `toml:"deny_common"`
+		DenyUsername  bool `toml:"deny_username"`
+	} `toml:"policy"`
+
+	Premium struct {
+		URL     string
+		Timeout int64
+	} `toml:"premium"`
+
+	Signing struct {
+		Key  string
+		Skew int64
+	} `toml:"signing"`
+
+	Idempotency struct {
+		Key    string
+		Window int64
+	} `toml:"idempotency"`
+
+	Wallet struct {
+		Default    string
+		Currencies []string
+		Limits     map[string]Limit
+	} `toml:"wallet"`
+
+	Store struct {
+		Secret   string
+		Header   string
+		Packages map[string]Package
+	} `toml:"store"`
+
+	Groups struct {
+		Key           string
+		PermissionKey string `toml:"permission_key"`
+	} `toml:"groups"`
+
+	Leaderboard struct {
+		Key string
+	} `toml:"leaderboard"`
+
+	Reconcile struct {
+		Interval  int64
+		Authority string
+	} `toml:"reconcile"`
+
+	Sweeper struct {
+		Interval int64
+		Batch    int
+		Channel  string
+	} `toml:"sweeper"`
+}
+
+// Range a currency balance must stay within, both ends are optional.
+type Limit struct {
+	Floor   *int64
+	Ceiling *int64
+}
+
+// Reward of a store package, cash and group are both optional.
+type Package struct {
+	Cash     int64
+	Currency string
+	Group    string
+	Duration int64
 }
 
 func Load(file string) (*Config, error) {
@@ -49,41 +165,337 @@ func Load(file string) (*Config, error) {
 }
 
 func (c *Config) GetParallelism() int {
-	return c.worker.Parallelism
+	return c.Worker.Parallelism
 }
 
 func (c *Config) GetRedis() string {
-	return c.redis.DSN
+	return c.Redis.DSN
 }
 
 func (c *Config) GetInterval() int {
-	return c.worker.Interval
+	return c.Worker.Interval
 }
 
 func (c *Config) GetIterations() int {
-	return c.worker.Iterations
+	return c.Worker.Iterations
 }
 
 func (c *Config) GetBinding() string {
-	return c.server.Binding
+	return c.Server.Binding
 }
 
 func (c *Config) GetMySQL() string {
-	return c.mysql.DSN
+	return c.MySQL.DSN
 }
 
+// Legacy single key holding every scope, prefer named keys from the keys command.
 func (c *Config) GetKey() string {
-	return c.api.Key
+	return c.API.Key
+}
+
+// Key to cache resolved API keys in redis.
+func (c *Config) GetAPIKeyCacheKey() string {
+	return c.API.CacheKey
+}
+
+// Time resolved API keys stay on cache, should be in seconds on config file.
+func (c *Config) GetAPIKeyCache() time.Duration {
+	return time.Duration(c.API.Cache) * time.Second
 }
 
 func (c *Config) GetDebug() bool {
-	return c.logging.Debug
+	return c.Logging.Debug
 }
 
+// Lifetime of cached accounts, should be in seconds on config file as documented there.
+// Older releases read the value as nanoseconds, which expired every key right away.
 func (c *Config) GetExpireInterval() time.Duration {
-	return time.Duration(c.redis.Interval)
+	return time.Duration(c.Redis.Interval) * time.Second
 }
 
 func (c *Config) GetAccountKey() string {
-	return c.redis.Key
+	return c.Redis.Key
+}
+
+func (c *Config) GetSessionSecret() string {
+	return c.Session.Secret
+}
+
+// Lifetime of access tokens, should be in seconds on config file.
+func (c *Config) GetAccessLifetime() time.Duration {
+	return time.Duration(c.Session.Access) * time.Second
+}
+
+// Lifetime of refresh tokens, should be in seconds on config file.
+func (c *Config) GetRefreshLifetime() time.Duration {
+	return time.Duration(c.Session.Refresh) * time.Second
+}
+
+func (c *Config) GetSessionKey() string {
+	return c.Session.Key
+}
+
+func (c *Config) GetLockoutKey() string {
+	return c.Lockout.Key
+}
+
+// Failed attempts allowed for a single username before locking it out.
+func (c *Config) GetLockoutThreshold() int64 {
+	return c.Lockout.Threshold
+}
+
+// Failed attempts allowed for a single address before locking it out.
+func (c *Config) GetLockoutAddressThreshold() int64 {
+	return c.Lockout.AddressThreshold
+}
+
+// Window where failed attempts are counted, should be in seconds on config file.
+func (c *Config) GetLockoutWindow() time.Duration {
+	return time.Duration(c.Lockout.Window) * time.Second
+}
+
+// First lockout duration, doubled on every further failure. Should be in seconds on config file.
+func (c *Config) GetLockoutBase() time.Duration {
+	return time.Duration(c.Lockout.Base) * time.Second
+}
+
+// Upper bound for a lockout, should be in seconds on config file.
+func (c *Config) GetLockoutMax() time.Duration {
+	return time.Duration(c.Lockout.Max) * time.Second
+}
+
+// Name shown by authenticator apps next to the account.
+func (c *Config) GetTwoFactorIssuer() string {
+	return c.TwoFactor.Issuer
+}
+
+func (c *Config) GetResetKey() string {
+	return c.Reset.Key
+}
+
+// Lifetime of password reset tokens, should be in seconds on config file.
+func (c *Config) GetResetLifetime() time.Duration {
+	return time.Duration(c.Reset.Lifetime) * time.Second
+}
+
+// Notifier used to deliver reset tokens, either "log" or "mail".
+func (c *Config) GetResetNotifier() string {
+	return c.Reset.Notifier
+}
+
+// File where the log notifier appends its messages, empty to only log them.
+func (c *Config) GetResetFile() string {
+	return c.Reset.File
+}
+
+func (c *Config) GetMailKey() string {
+	return c.Mail.Key
+}
+
+// Time undelivered in-game mail is kept, should be in seconds on config file.
+func (c *Config) GetMailLifetime() time.Duration {
+	return time.Duration(c.Mail.Lifetime) * time.Second
+}
+
+// Algorithm used for new password hashes, either "argon2id" or "bcrypt".
+func (c *Config) GetPasswordAlgorithm() string {
+	return c.Password.Algorithm
+}
+
+func (c *Config) GetBcryptCost() int {
+	return c.Password.Cost
+}
+
+// Memory used by argon2id, should be in KiB on config file.
+func (c *Config) GetArgon2Memory() uint32 {
+	return c.Password.Memory
+}
+
+func (c *Config) GetArgon2Iterations() uint32 {
+	return c.Password.Iterations
+}
+
+func (c *Config) GetArgon2Parallelism() uint8 {
+	return c.Password.Parallelism
+}
+
+// Regular expression every registered username must match.
+func (c *Config) GetUsernamePattern() string {
+	return c.Policy.Username
+}
+
+func (c *Config) GetMinPassword() int {
+	return c.Policy.MinPassword
+}
+
+func (c *Config) GetMaxPassword() int {
+	return c.Policy.MaxPassword
+}
+
+func (c *Config) GetRequireLower() bool {
+	return c.Policy.RequireLower
+}
+
+func (c *Config) GetRequireUpper() bool {
+	return c.Policy.RequireUpper
+}
+
+func (c *Config) GetRequireDigit() bool {
+	return c.Policy.RequireDigit
+}
+
+func (c *Config) GetRequireSymbol() bool {
+	return c.Policy.RequireSymbol
+}
+
+// Whether passwords from the bundled common password list are refused.
+func (c *Config) GetDenyCommon() bool {
+	return c.Policy.DenyCommon
+}
+
+// Whether passwords containing the username are refused.
+func (c *Config) GetDenyUsername() bool {
+	return c.Policy.DenyUsername
+}
+
+// Mojang compatible hasJoined endpoint used to verify premium accounts.
+func (c *Config) GetSessionServer() string {
+	return c.Premium.URL
+}
+
+// Timeout for session server requests, should be in seconds on config file.
+func (c *Config) GetSessionServerTimeout() time.Duration {
+	return time.Duration(c.Premium.Timeout) * time.Second
+}
+
+// Prefix of the nonces remembered to reject replayed signed requests.
+func (c *Config) GetSigningKey() string {
+	return c.Signing.Key
+}
+
+// Maximum clock difference accepted on signed requests, should be in seconds on config file.
+func (c *Config) GetSigningSkew() time.Duration {
+	return time.Duration(c.Signing.Skew) * time.Second
+}
+
+// Key to store responses of requests carrying an Idempotency-Key in redis.
+func (c *Config) GetIdempotencyKey() string {
+	return c.Idempotency.Key
+}
+
+// How long replays return the stored response, should be in seconds on config file.
+func (c *Config) GetIdempotencyWindow() time.Duration {
+	return time.Duration(c.Idempotency.Window) * time.Second
+}
+
+// Currency stored on the account cash, used by cash routes without a currency.
+func (c *Config) GetDefaultCurrency() string {
+	if len(c.Wallet.Default) == 0 {
+		return "cash"
+	}
+
+	return c.Wallet.Default
+}
+
+// Currencies created on startup, besides the default one.
+func (c *Config) GetCurrencies() []string {
+	return c.Wallet.Currencies
+}
+
+// Lowest and highest balance allowed on the currency, from zero up to the 64 bits limit by default.
+func (c *Config) GetCurrencyLimits(currency string) (int64, int64) {
+	floor, ceiling := int64(0), int64(math.MaxInt64)
+
+	if limit, ok := c.Wallet.Limits[currency]; ok {
+		if limit.Floor != nil {
+			floor = *limit.Floor
+		}
+
+		if limit.Ceiling != nil {
+			ceiling = *limit.Ceiling
+		}
+	}
+
+	return floor, ceiling
+}
+
+// Secret the web store signs its webhooks with, the webhook is disabled when empty.
+func (c *Config) GetStoreSecret() string {
+	return c.Store.Secret
+}
+
+// Header carrying the hex HMAC-SHA256 of the webhook body.
+func (c *Config) GetStoreHeader() string {
+	if len(c.Store.Header) == 0 {
+		return "X-Store-Signature"
+	}
+
+	return c.Store.Header
+}
+
+// Reward of the package sold by the store, duration should be in seconds on config file.
+func (c *Config) GetStorePackage(id string) (Package, bool) {
+	pkg, ok := c.Store.Packages[id]
+
+	return pkg, ok
+}
+
+// Key to cache the group registry in redis.
+func (c *Config) GetGroupKey() string {
+	if len(c.Groups.Key) == 0 {
+		return "groups"
+	}
+
+	return c.Groups.Key
+}
+
+// Key to cache the resolved permissions of each account in redis.
+func (c *Config) GetPermissionKey() string {
+	if len(c.Groups.PermissionKey) == 0 {
+		return "permissions"
+	}
+
+	return c.Groups.PermissionKey
+}
+
+// Prefix of the sorted sets ranking the balances of each currency.
+func (c *Config) GetLeaderboardKey() string {
+	if len(c.Leaderboard.Key) == 0 {
+		return "leaderboard"
+	}
+
+	return c.Leaderboard.Key
+}
+
+// Time between background reconciliations of cache and database, should be in seconds on config file.
+func (c *Config) GetReconcileInterval() time.Duration {
+	return time.Duration(c.Reconcile.Interval) * time.Second
+}
+
+// Copy kept by background reconciliations on drift, either "none", "db" or "cache".
+func (c *Config) GetReconcileAuthority() string {
+	return c.Reconcile.Authority
+}
+
+// Time between sweeps of expired group grants, should be in seconds on config file.
+func (c *Config) GetSweepInterval() time.Duration {
+	return time.Duration(c.Sweeper.Interval) * time.Second
+}
+
+// Expired grants removed on each batch of a sweep.
+func (c *Config) GetSweepBatch() int {
+	if c.Sweeper.Batch <= 0 {
+		return 500
+	}
+
+	return c.Sweeper.Batch
+}
+
+// Redis channel on which every expired grant is published.
+func (c *Config) GetSweepChannel() string {
+	if len(c.Sweeper.Channel) == 0 {
+		return "group-expiry"
+	}
+
+	return c.Sweeper.Channel
 }
@@ -1,19 +1,128 @@
 package data
 
 import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
+var contextPattern = regexp.MustCompile(`^[a-z0-9_\-]{1,32}$`)
+
+// Where a grant applies, such as {"server": "survival"}. Grants without context apply everywhere.
+type GroupContext map[string]string
+
+// Parse the "key:value,key:value" form the context is stored and queried on.
+func ParseGroupContext(value string) (GroupContext, error) {
+	context := GroupContext{}
+
+	if len(value) == 0 {
+		return context, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		key, entry, ok := strings.Cut(pair, ":")
+
+		if !ok {
+			return nil, errors.New("context pairs must be written as key:value")
+		}
+
+		context[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(entry))
+	}
+
+	return context, context.Validate()
+}
+
+func (context GroupContext) Validate() error {
+	for key, value := range context {
+		if !contextPattern.MatchString(key) || !contextPattern.MatchString(value) {
+			return errors.New("context keys and values must be 1 to 32 lowercase letters, digits, dashes or underscores")
+		}
+	}
+
+	if len(context.String()) > 255 {
+		return errors.New("context must have at most 255 characters")
+	}
+
+	return nil
+}
+
+// Whether the grant applies on the target, every pair of the grant must be on the target.
+func (context GroupContext) Matches(target GroupContext) bool {
+	for key, value := range context {
+		if target[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pairs sorted by key, so equal contexts are written the same.
+func (context GroupContext) String() string {
+	pairs := make([]string, 0, len(context))
+
+	for key, value := range context {
+		pairs = append(pairs, key+":"+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (context GroupContext) Value() (driver.Value, error) {
+	return context.String(), nil
+}
+
+func (context *GroupContext) Scan(value interface{}) error {
+	var source string
+
+	switch target := value.(type) {
+	case []byte:
+		source = string(target)
+	case string:
+		source = target
+	case nil:
+	default:
+		return errors.New("cannot scan context from " + fmt.Sprint(value))
+	}
+
+	parsed, err := ParseGroupContext(source)
+
+	if err != nil {
+		return err
+	}
+
+	*context = parsed
+
+	return nil
+}
+
 type GroupInfo struct {
 	User string `json:"-" gorm:"column:user;type:char(36);not null"`
 
 	Group  GroupType `json:"group" gorm:"column:role;type:varchar(18);not null"`
 	Author string    `json:"author" gorm:"column:author;type:char(36);not null"`
 
+	Context GroupContext `json:"context" gorm:"column:context;type:varchar(255);not null;default:''"`
+
 	ExpireAt  time.Time `json:"expire_at" gorm:"column:expire_at;not null;default:CURRENT_TIMESTAMP();"`
 	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP();"`
 }
 
+// Identifies the grant among the ones of the account, the group followed by "@" and its context when it has one.
+func (info GroupInfo) Key() string {
+	if len(info.Context) == 0 {
+		return string(info.Group)
+	}
+
+	return string(info.Group) + "@" + info.Context.String()
+}
+
 type MetadataSet struct {
 	User string `json:"-" gorm:"column:user;type:char(36);not null"`
 
@@ -34,18 +143,21 @@ type Account interface {
 	GetName() string
 
 	GetAccountType() AccountType
+	GetPremiumId() string
+
+	GetCash() int64
+	SetCash(cash int64)
+	AddCash(amount int64)
+	TakeCash(amount int64)
 
-	GetCash() int32
-	SetCash(cash int32)
-	AddCash(amount int32)
-	TakeCash(amount int32)
+	GetWallets() []Wallet
 
 	GetMetadataSet() MetadataSet
 	GetGroupSet() []GroupInfo
 
 	AddGroup(group GroupInfo)
-	RemoveGroup(group GroupType)
-	HasGroupSet(group GroupType) bool
+	RemoveGroup(group GroupType, context GroupContext)
+	HasGroupSet(group GroupType, context GroupContext) bool
 
 	GetCreatedAt() time.Time
 	GetUpdatedAt() time.Time
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"time"
+)
+
+type Scope string
+
+const (
+	ALL_SCOPES Scope = "*"
+
+	ACCOUNT_READ  Scope = "account:read"
+	ACCOUNT_WRITE Scope = "account:write"
+	CASH_WRITE    Scope = "cash:write"
+	GROUP_WRITE   Scope = "group:write"
+	AUTH_WRITE    Scope = "auth:write"
+	AUTH_ADMIN    Scope = "auth:admin"
+
+	CURRENCY_WRITE Scope = "currency:write"
+	COUPON_ADMIN   Scope = "coupon:admin"
+	COUPON_REDEEM  Scope = "coupon:redeem"
+	GROUP_ADMIN    Scope = "group:admin"
+)
+
+// Every scope a key can be granted.
+var Scopes = []Scope{
+	ALL_SCOPES,
+	ACCOUNT_READ, ACCOUNT_WRITE, CASH_WRITE, GROUP_WRITE, AUTH_WRITE, AUTH_ADMIN,
+	CURRENCY_WRITE, COUPON_ADMIN, COUPON_REDEEM, GROUP_ADMIN,
+}
+
+func (scope Scope) IsKnown() bool {
+	for _, known := range Scopes {
+		if known == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type APIKey interface {
+	GetUniqueId() string
+	GetName() string
+
+	GetSigningSecret() string
+	RequiresSigning() bool
+
+	GetScopes() []Scope
+	HasScope(scope Scope) bool
+
+	GetAddresses() []string
+	AllowsAddress(address string) bool
+
+	GetExpireAt() *time.Time
+	IsExpired() bool
+	IsRevoked() bool
+
+	GetCreatedAt() time.Time
+}
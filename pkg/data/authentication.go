@@ -13,6 +13,8 @@ type Authentication interface {
 	GetCreatedAt() time.Time
 	GetUpdatedAt() time.Time
 
+	HasTwoFactor() bool
+
 	CheckPassword(password string) bool
 	UpdatePassword(password string)
 }
@@ -20,4 +22,7 @@ type Authentication interface {
 type LoginRequest interface {
 	GetUsername() string
 	GetPassword() string
+
+	// Address of the player, given by the game server since requests come from its own address.
+	GetAddress() string
 }
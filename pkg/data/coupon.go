@@ -0,0 +1,40 @@
+package data
+
+import (
+	"time"
+)
+
+type Coupon interface {
+	GetUniqueId() string
+	GetBatch() string
+
+	GetCurrency() string
+	GetCash() int64
+
+	GetGroup() GroupType
+	GetDuration() time.Duration
+
+	GetMaxRedemptions() int64
+	GetPerAccount() int64
+	GetRedemptions() int64
+
+	GetAuthor() string
+
+	GetExpireAt() *time.Time
+	GetCreatedAt() time.Time
+
+	IsExpired() bool
+}
+
+type Redemption interface {
+	GetCoupon() string
+	GetUser() string
+
+	GetCurrency() string
+	GetCash() int64
+
+	GetGroup() GroupType
+	GetExpireAt() *time.Time
+
+	GetCreatedAt() time.Time
+}
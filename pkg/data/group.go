@@ -0,0 +1,19 @@
+package data
+
+import (
+	"time"
+)
+
+// Definition of a group on the registry, the weight orders groups from the highest rank.
+type Group interface {
+	GetName() GroupType
+
+	GetPrefix() string
+	GetColor() string
+	GetWeight() int
+
+	IsDefault() bool
+
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+}
@@ -0,0 +1,23 @@
+package data
+
+import (
+	"time"
+)
+
+type Purchase interface {
+	GetUniqueId() string
+	GetTransaction() string
+	GetUser() string
+	GetPackage() string
+
+	GetCurrency() string
+	GetCash() int64
+
+	GetGroup() GroupType
+	GetDuration() time.Duration
+
+	GetStatus() string
+
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+}
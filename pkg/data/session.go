@@ -0,0 +1,19 @@
+package data
+
+import (
+	"time"
+)
+
+type Session interface {
+	GetUniqueId() string
+	GetUser() string
+
+	GetAddress() string
+	GetAgent() string
+
+	GetCreatedAt() time.Time
+	GetLastSeenAt() time.Time
+	GetExpireAt() time.Time
+
+	IsRevoked() bool
+}
@@ -9,6 +9,7 @@ const (
 
 type GroupType string
 
+// Groups seeded into the registry on first start, more are defined through /v1/groups.
 const (
 	OWNER     GroupType = "OWNER"
 	ADMIN     GroupType = "ADMIN"
@@ -25,5 +26,5 @@ const (
 )
 
 type UUIDData struct {
-	UUID string `gorm:"primaryKey;type:char(36)" json:"unique_id"`
+	UUID string `gorm:"primaryKey;type:char(36);column:unique_id" json:"unique_id"`
 }
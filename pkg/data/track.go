@@ -0,0 +1,72 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Groups of a track from the lowest rank, stored as "HELPER,MODERATOR,ADMIN".
+type GroupLadder []GroupType
+
+// Position of the group on the ladder, -1 when it is not on it.
+func (ladder GroupLadder) Index(group GroupType) int {
+	for i, target := range ladder {
+		if target == group {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (ladder GroupLadder) String() string {
+	groups := make([]string, 0, len(ladder))
+
+	for _, group := range ladder {
+		groups = append(groups, string(group))
+	}
+
+	return strings.Join(groups, ",")
+}
+
+func (ladder GroupLadder) Value() (driver.Value, error) {
+	return ladder.String(), nil
+}
+
+func (ladder *GroupLadder) Scan(value interface{}) error {
+	var source string
+
+	switch target := value.(type) {
+	case []byte:
+		source = string(target)
+	case string:
+		source = target
+	case nil:
+	default:
+		return errors.New("cannot scan ladder from " + fmt.Sprint(value))
+	}
+
+	*ladder = GroupLadder{}
+
+	if len(source) == 0 {
+		return nil
+	}
+
+	for _, group := range strings.Split(source, ",") {
+		*ladder = append(*ladder, GroupType(group))
+	}
+
+	return nil
+}
+
+// Ladder staff climb through promotions, such as HELPER, MODERATOR and then ADMIN.
+type Track interface {
+	GetName() string
+	GetGroups() GroupLadder
+
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+}
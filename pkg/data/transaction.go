@@ -0,0 +1,23 @@
+package data
+
+import (
+	"time"
+)
+
+type Transaction interface {
+	GetId() uint64
+	GetUser() string
+	GetCurrency() string
+
+	GetAmount() int64
+	GetBalance() int64
+
+	GetReason() string
+	GetSource() string
+	GetActor() string
+	GetCounterparty() string
+
+	GetIdempotencyKey() string
+
+	GetCreatedAt() time.Time
+}
@@ -0,0 +1,20 @@
+package data
+
+import (
+	"time"
+)
+
+// Balance of an account on a currency other than the default one, which stays on the account cash.
+type Wallet struct {
+	User     string `json:"-" gorm:"column:user;type:char(36);primaryKey"`
+	Currency string `json:"currency" gorm:"column:currency;type:varchar(32);primaryKey"`
+
+	Balance int64 `json:"balance" gorm:"column:balance;type:bigint;not null;default:0"`
+
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+type Currency interface {
+	GetName() string
+	GetCreatedAt() time.Time
+}
@@ -0,0 +1,43 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Headers carried by signed requests, used instead of the Authorization one.
+const (
+	HeaderKey       = "X-Galax-Key"
+	HeaderTimestamp = "X-Galax-Timestamp"
+	HeaderNonce     = "X-Galax-Nonce"
+	HeaderSignature = "X-Galax-Signature"
+)
+
+// Canonical form of a request, both sides must build it the same way.
+//
+//	METHOD\nPATH?QUERY\nhex(sha256(body))\ntimestamp\nnonce
+func Canonical(method, path string, body []byte, timestamp int64, nonce string) string {
+	sum := sha256.Sum256(body)
+
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		hex.EncodeToString(sum[:]),
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+	}, "\n")
+}
+
+func Compute(secret, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret, canonical, signature string) bool {
+	return hmac.Equal([]byte(Compute(secret, canonical)), []byte(strings.ToLower(signature)))
+}
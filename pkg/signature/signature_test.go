@@ -0,0 +1,79 @@
+package signature
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      []byte
+		timestamp int64
+		nonce     string
+		canonical string
+	}{
+		{
+			"empty body", "get", "/v1/account?id=1", nil, 1700000000, "abc",
+			"GET\n/v1/account?id=1\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n1700000000\nabc",
+		},
+		{
+			"json body", "POST", "/v1/account/cash?id=1", []byte(`{"amount":10}`), 1700000000, "abc",
+			"POST\n/v1/account/cash?id=1\n" + hashOf(`{"amount":10}`) + "\n1700000000\nabc",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if canonical := Canonical(test.method, test.path, test.body, test.timestamp, test.nonce); canonical != test.canonical {
+				t.Errorf("Canonical = %q, want %q", canonical, test.canonical)
+			}
+		})
+	}
+}
+
+func hashOf(body string) string {
+	sum := sha256.Sum256([]byte(body))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCompute(t *testing.T) {
+	// Well known HMAC-SHA256 vector.
+	if signature := Compute("key", "The quick brown fox jumps over the lazy dog"); signature != "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8" {
+		t.Errorf("Compute = %s", signature)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	canonical := Canonical("POST", "/v1/account/cash?id=1", []byte(`{"amount":10}`), 1700000000, "abc")
+	signature := Compute("secret", canonical)
+
+	tests := []struct {
+		name      string
+		secret    string
+		canonical string
+		signature string
+		valid     bool
+	}{
+		{"matching", "secret", canonical, signature, true},
+		{"uppercase hex", "secret", canonical, strings.ToUpper(signature), true},
+		{"other secret", "other", canonical, signature, false},
+		{"tampered body", "secret", Canonical("POST", "/v1/account/cash?id=1", []byte(`{"amount":99}`), 1700000000, "abc"), signature, false},
+		{"other nonce", "secret", Canonical("POST", "/v1/account/cash?id=1", []byte(`{"amount":10}`), 1700000000, "abd"), signature, false},
+		{"truncated", "secret", canonical, signature[:32], false},
+		{"empty", "secret", canonical, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := Verify(test.secret, test.canonical, test.signature); valid != test.valid {
+				t.Errorf("Verify = %t, want %t", valid, test.valid)
+			}
+		})
+	}
+}